@@ -1,18 +1,79 @@
 // package gpsinfo decodes gps nmea frames from your gpsdongle (debug/ingo)
 package gpsinfo
 
-import "paepcke.de/gpsinfo/gpsfeed"
+import (
+	"context"
+	"time"
+
+	"paepcke.de/gpsinfo/epoch"
+	"paepcke.de/gpsinfo/gpsfeed"
+)
+
+// RenderRate is the max display refresh rate [Hz], the device feed is always read completely [<= 0: render every epoch]
+var RenderRate = 5.0
+
+//
+// STATE
+//
+
+// State is the merged view of a receiver after a complete epoch, position, velocity, fix quality,
+// dilution, satellites [see epoch.Fix], plus gps time and the local clock offset
+type State struct {
+	epoch.Fix
+	Device  string        // device name
+	GPSTime time.Time     // utc fix date and time [RMC], zero if unknown
+	Offset  time.Duration // gps time minus local receive time of the epoch, zero if unknown
+	Dropped uint64        // sentences dropped since the device got opened, consumer too slow
+}
 
 //
 // SIMPLE API
 //
 
 // Debug ...
-func Debug(device string) { debug(&gpsfeed.GpsDevice{FileIO: device}) }
+func Debug(device string) { debug(context.Background(), &gpsfeed.GpsDevice{FileIO: device}) }
+
+// DebugN2K decodes NMEA 2000 frames from a can interface (eg. can0) or a candump log file
+func DebugN2K(source string) error { return debugN2K(context.Background(), source) }
+
+// Compare shows several receivers side by side, incl. the position delta between them
+func Compare(devices ...string) error {
+	devs := make([]*gpsfeed.GpsDevice, 0, len(devices))
+	for _, device := range devices {
+		devs = append(devs, &gpsfeed.GpsDevice{FileIO: device})
+	}
+	return compare(context.Background(), devs)
+}
+
+// Scan probes all serial, usb and gps devices and reports the gps receivers found, their rate, talkers and model
+func Scan() error { return scan(context.Background(), 0) }
+
+// Updates returns a State for every epoch of the device, without display, forever
+func Updates(device string) <-chan State {
+	return subscribe(context.Background(), &gpsfeed.GpsDevice{FileIO: device}, 1)
+}
 
 //
 // GENERIC BACKEND
 //
 
 // DebugD ...
-func DebugD(dev *gpsfeed.GpsDevice) { debug(dev) }
+func DebugD(dev *gpsfeed.GpsDevice) { debug(context.Background(), dev) }
+
+// DebugContext runs until ctx is done, stops all goroutines and watchdogs and returns the ctx error
+func DebugContext(ctx context.Context, dev *gpsfeed.GpsDevice) error { return debug(ctx, dev) }
+
+// CompareContext runs until ctx is done
+func CompareContext(ctx context.Context, devs ...*gpsfeed.GpsDevice) error { return compare(ctx, devs) }
+
+// DebugN2KContext runs until the source ends or ctx is done
+func DebugN2KContext(ctx context.Context, source string) error { return debugN2K(ctx, source) }
+
+// ScanContext probes each device for the time window [0: 2s]
+func ScanContext(ctx context.Context, window time.Duration) error { return scan(ctx, window) }
+
+// Subscribe returns a State for every epoch of the device, without display. The channel is buffered
+// with size, it is closed once ctx is done or the device reconnect policy gives up.
+func Subscribe(ctx context.Context, dev *gpsfeed.GpsDevice, size int) <-chan State {
+	return subscribe(ctx, dev, size)
+}
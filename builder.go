@@ -8,20 +8,22 @@ import (
 
 	"paepcke.de/airloctag"
 	"paepcke.de/daylight/sun"
+	"paepcke.de/gpsinfo/epoch"
 	"paepcke.de/gpsinfo/geohash"
 	"paepcke.de/gpsinfo/gpsfeed"
 	"paepcke.de/gpsinfo/nmeanano"
+	"paepcke.de/gpsinfo/rtcm3"
+	"paepcke.de/gpsinfo/ubx"
 	"paepcke.de/gpsinfo/zlatlong"
 )
 
 const _TS = "15:04:05" // time stamp layout [time.Parse]
 
 // build ...
-func build(dev *gpsfeed.GpsDevice, channelGpsFrames, channelOut chan string, displaySleep time.Duration) {
+func build(dev *gpsfeed.GpsDevice, stats *feedStats, channelGpsFrames chan nmeanano.Sentence, channelOut chan string, th *throttle) {
 	var (
 		counter, fix          int64
 		fixQuality            string
-		err                   error
 		cDIFF, cXX            string
 		NumberSVsInView       string
 		indicator             string
@@ -41,6 +43,8 @@ func build(dev *gpsfeed.GpsDevice, channelGpsFrames, channelOut chan string, dis
 		y nmeanano.GNS
 		m nmeanano.RMC
 		o nmeanano.VDMVDO
+		h ubx.MonHW
+		r rtcm3.Status
 	)
 	// set defaults
 	m.BaseSentence.Raw = _defaults
@@ -50,48 +54,61 @@ func build(dev *gpsfeed.GpsDevice, channelGpsFrames, channelOut chan string, dis
 	x.BaseSentence.Raw = _defaults
 	y.BaseSentence.Raw = _defaults
 	o.BaseSentence.Raw = _defaults
+	h.AntennaStatus, h.AntennaPower, h.JammingState = _defaultsShort, _defaultsShort, _defaultsShort
 
-	// main loop / sentence subscriber
-	for line := range channelGpsFrames {
+	// main loop / epoch subscriber, applies all sentences of an epoch at once
+	epochs := &epoch.Assembler{MaxAge: maxDiff}
+	for in := range channelGpsFrames {
 		tsSys = time.Now()
-		var b strings.Builder
-		if s, err = nmeanano.Parse(line); err != nil {
+		ep, closed := epochs.Add(in, tsSys)
+		if !closed {
 			continue
 		}
-		switch s.DataType() {
-		case "RMC":
-			m = s.(nmeanano.RMC)
-			tsGps = nmeanano.GetTimeStamp(m)
-		case "GSV":
-			g = s.(nmeanano.GSV)
-			if g.NumberSVsInView < 6 {
-				cXX = _ALERT
-			} else {
-				cXX = _ALERT_G
+		for _, s = range ep.Sentences {
+			switch s.DataType() {
+			case "RMC":
+				m = s.(nmeanano.RMC)
+				tsGps = nmeanano.GetTimeStamp(m)
+			case "GSV":
+				g = s.(nmeanano.GSV)
+				if g.NumberSVsInView < 6 {
+					cXX = _ALERT
+				} else {
+					cXX = _ALERT_G
+				}
+				indicator = cXX + strings.Repeat("|", int(g.NumberSVsInView*4)) + _OFF
+				NumberSVsInView = fmt.Sprintf("%s %s[%v]%s", indicator, _CYAN, g.NumberSVsInView, _OFF)
+			case "GSA":
+				a = s.(nmeanano.GSA)
+			case "GGA":
+				x = s.(nmeanano.GGA)
+				fix, _ = strconv.ParseInt(x.FixQuality, 10, 0)
+				if int(fix) > 0 {
+					fixQuality = "ok"
+				} else {
+					fixQuality = "invalid"
+				}
+			case "VTG":
+				v = s.(nmeanano.VTG)
+			case "GNS":
+				y = s.(nmeanano.GNS)
+			case "VDO":
+				o = s.(nmeanano.VDMVDO)
+			case "VDM":
+				o = s.(nmeanano.VDMVDO)
+			case ubx.TypeMonHW:
+				h = s.(ubx.MonHW)
+			case rtcm3.TypeRTCM3:
+				r = s.(rtcm3.Status)
+			default:
+				continue
 			}
-			indicator = cXX + strings.Repeat("|", int(g.NumberSVsInView*4)) + _OFF
-			NumberSVsInView = fmt.Sprintf("%s %s[%v]%s", indicator, _CYAN, g.NumberSVsInView, _OFF)
-		case "GSA":
-			a = s.(nmeanano.GSA)
-		case "GGA":
-			x = s.(nmeanano.GGA)
-			fix, _ = strconv.ParseInt(x.FixQuality, 10, 0)
-			if int(fix) > 0 {
-				fixQuality = "ok"
-			} else {
-				fixQuality = "invalid"
-			}
-		case "VTG":
-			v = s.(nmeanano.VTG)
-		case "GNS":
-			y = s.(nmeanano.GNS)
-		case "VDO":
-			o = s.(nmeanano.VDMVDO)
-		case "VDM":
-			o = s.(nmeanano.VDMVDO)
-		default:
-			continue
+			counter++
 		}
+		if !th.due(tsSys) {
+			continue // state is current, render at most RenderRate epochs per second
+		}
+		var b strings.Builder
 		if oldLat != m.Latitude && oldLong != m.Longitude {
 			oldLat, oldLong = m.Latitude, m.Longitude
 			dist = displayMCD(m.Latitude, m.Longitude, x.Altitude)
@@ -143,6 +160,7 @@ func build(dev *gpsfeed.GpsDevice, channelGpsFrames, channelOut chan string, dis
 		fmt.Fprint(&b, _sectionLine)
 		fmt.Fprintf(&b, "Fix Dilution         : Type %s%v%s Mode %s%v%s Precision Dilution %s%v%s ( Horizontal %s%v%s Vertical %s%v%s )\n", _BLUE, a.Mode, _OFF, _BLUE, a.Type, _OFF, _BLUE, a.PDOP, _OFF, _BLUE, a.HDOP, _OFF, _BLUE, a.VDOP, _OFF)
 		fmt.Fprintf(&b, "Fix Quality          : %s[%s]%s\n", _ALERT_G, fixQuality, _OFF)
+		fmt.Fprintf(&b, "Antenna [ubx]        : Status %s%v%s Power %s%v%s Jamming %s%v%s [%s%v%s]\n", _BLUE, h.AntennaStatus, _OFF, _BLUE, h.AntennaPower, _OFF, _BLUE, h.JammingState, _OFF, _BLUE, h.JamInd, _OFF)
 		fmt.Fprintf(&b, "Fix used Sat's       : %s%v%s\n", _BLUE, x.NumSatellites, _OFF)
 		fmt.Fprintf(&b, "Fix Time             : %s%v%s\n", _BLUE, x.Time, _OFF)
 		if m.Date.Valid && m.Time.Valid {
@@ -150,7 +168,7 @@ func build(dev *gpsfeed.GpsDevice, channelGpsFrames, channelOut chan string, dis
 		} else {
 			fmt.Fprintf(&b, "Validity             : %s%s [faild]%s\n", _ALERT, m.Validity, _OFF)
 		}
-		t = tsGps.Sub(tsSys)
+		t = tsGps.Sub(ep.Opened) // epoch receive time
 		if t < maxDiff {
 			cDIFF = _ALERT_G
 		} else {
@@ -160,10 +178,34 @@ func build(dev *gpsfeed.GpsDevice, channelGpsFrames, channelOut chan string, dis
 		fmt.Fprintf(&b, "GPS Time             : %s%s%s\n", _BLUE, tsGps, _OFF)
 		fmt.Fprintf(&b, "Local System Time    : %s%s%s\n", _BLUE, tsSys, _OFF)
 		fmt.Fprint(&b, _sectionLine)
-		counter++
+		if len(r.Types) > 0 {
+			age := tsSys.Sub(r.Last).Round(time.Millisecond)
+			if age < maxDiff {
+				cDIFF = _ALERT_G
+			} else {
+				cDIFF = _ALERT
+			}
+			fmt.Fprintf(&b, "RTCM3 Correction Age : %s%v%s\n", cDIFF, age, _OFF)
+			for i := range r.Types {
+				fmt.Fprintf(&b, " + Type %s%4d%s %s Count %s%6d%s Rate %s%5.2f%s [Hz]\n", _BLUE, r.Types[i].Type, _OFF, pad(r.Types[i].Name), _BLUE, r.Types[i].Count, _OFF, _BLUE, r.Types[i].Rate, _OFF)
+			}
+			fmt.Fprint(&b, _sectionLine)
+		}
+		fmt.Fprintf(&b, "Feed Health          : Errors %s%v%s Garbage %s%v%s [bytes] Dropped %s%v%s\n", _BLUE, dev.ErrCount.Load(), _OFF, _BLUE, dev.Garbage.Load(), _OFF, _BLUE, stats.dropped.Load(), _OFF)
+		for _, st := range dev.Stats() {
+			fmt.Fprintf(&b, " + %s%-4s%s %s%-6s%s Count %s%6d%s Rate %s%5.2f%s [Hz] Interval %s%8v%s Jitter %s%8v%s Checksum %s%d%s Parse %s%d%s Unsupported %s%d%s\n", _BLUE, st.Talker, _OFF, _BLUE, st.Type, _OFF, _BLUE, st.Count, _OFF, _BLUE, st.Rate, _OFF, _BLUE, st.Interval.Round(time.Millisecond), _OFF, _BLUE, st.Jitter.Round(time.Millisecond), _OFF, healthColor(st.Checksum), st.Checksum, _OFF, healthColor(st.Parse), st.Parse, _OFF, healthColor(st.Unsupported), st.Unsupported, _OFF)
+		}
+		fmt.Fprint(&b, _sectionLine)
 		dtime = (time.Since(tsSys))
-		fmt.Fprintf(&b, "%s###  Time needed to decode Frame: %v  ###  Total Frames: %v%s\n", _GREY, dtime, counter, _OFF)
+		fmt.Fprintf(&b, "%s###  Time needed to decode Frame: %v  ###  Total Frames: %v  ###  Dropped Frames: %v%s\n", _GREY, dtime, counter, stats.dropped.Load(), _OFF)
 		channelOut <- b.String()
-		time.Sleep(displaySleep)
 	}
 }
+
+// healthColor highlights non-zero error counters
+func healthColor(n uint64) string {
+	if n > 0 {
+		return _ALERT
+	}
+	return _BLUE
+}
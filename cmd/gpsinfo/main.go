@@ -3,20 +3,94 @@ package main
 
 // import
 import (
+	"context"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"paepcke.de/gpsinfo"
 	"paepcke.de/gpsinfo/gpsfeed"
 )
 
 // const defaults
-const _defaultDevice = "/dev/gps0"
+const (
+	_defaultDevice = "/dev/gps0"
+	_profiles      = "profiles="
+)
 
 // main ...
 func main() {
-	port := _defaultDevice
+	// SIGINT/SIGTERM stop all feeds and restore the terminal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if len(os.Args) == 3 && os.Args[1] == "n2k" {
+		if err := gpsinfo.DebugN2KContext(ctx, os.Args[2]); err != nil && ctx.Err() == nil {
+			os.Stdout.Write([]byte("[error] [n2k] [" + err.Error() + "]\n"))
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) == 2 && os.Args[1] == "scan" {
+		if err := gpsinfo.ScanContext(ctx, 0); err != nil && ctx.Err() == nil {
+			os.Stdout.Write([]byte("[error] [" + err.Error() + "]\n"))
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 2 && os.Args[1] == "compare" {
+		var devs []*gpsfeed.GpsDevice
+		var profiles gpsfeed.Profiles
+		for _, arg := range os.Args[2:] {
+			if file, ok := strings.CutPrefix(arg, _profiles); ok {
+				profiles = loadProfiles(file)
+				continue
+			}
+			if name := gpsfeed.GetDeviceName(arg, ""); name != "" {
+				devs = append(devs, &gpsfeed.GpsDevice{FileIO: name})
+			}
+		}
+		for _, dev := range devs {
+			dev.Profile = profiles.Lookup(dev.FileIO)
+		}
+		gpsinfo.CompareContext(ctx, devs...)
+		return
+	}
+	var auto bool
+	var profiles gpsfeed.Profiles
+	dev := &gpsfeed.GpsDevice{FileIO: _defaultDevice}
 	for i := 1; i < len(os.Args); i++ {
-		port = gpsfeed.GetDeviceName(os.Args[i], port)
+		if os.Args[i] == "auto" {
+			auto = true
+			continue
+		}
+		if file, ok := strings.CutPrefix(os.Args[i], _profiles); ok {
+			profiles = loadProfiles(file)
+			continue
+		}
+		if c, err := gpsfeed.ParseSerialConfig(os.Args[i]); err == nil {
+			os.Stdout.Write([]byte("[info] [serial] [" + c.String() + "]\n"))
+			dev.Serial = &c
+			continue
+		}
+		dev.FileIO = gpsfeed.GetDeviceName(os.Args[i], dev.FileIO)
+	}
+	dev.Profile = profiles.Lookup(dev.FileIO)
+	if auto {
+		if _, err := dev.AutoBaudContext(ctx, 0); err != nil {
+			os.Exit(1)
+		}
+	}
+	gpsinfo.DebugContext(ctx, dev)
+}
+
+// loadProfiles reads the receiver profile file, exits on error
+func loadProfiles(file string) gpsfeed.Profiles {
+	profiles, err := gpsfeed.LoadProfiles(file)
+	if err != nil {
+		os.Stdout.Write([]byte("[error] [profiles] [" + err.Error() + "]\n"))
+		os.Exit(1)
 	}
-	gpsinfo.Debug(port)
+	os.Stdout.Write([]byte("[info] [profiles] [" + file + "]\n"))
+	return profiles
 }
@@ -0,0 +1,150 @@
+package gpsinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"paepcke.de/gpsinfo/epoch"
+	"paepcke.de/gpsinfo/gpsfeed"
+	"paepcke.de/gpsinfo/nmeanano"
+	"paepcke.de/gpsinfo/ubx"
+)
+
+const (
+	_compareHistory   = 16                    // epochs kept per receiver for the pairing
+	_compareTolerance = 50 * time.Millisecond // max epoch time difference of a paired fix
+)
+
+// receiverState holds the recent complete epochs of a single receiver
+type receiverState struct {
+	dev     *gpsfeed.GpsDevice
+	fix     epoch.Fix   // last epoch
+	fixes   []epoch.Fix // recent epochs with valid time, oldest first
+	epochs  epoch.Assembler
+	decoder *ubx.Decoder
+}
+
+// compare runs all devices side by side and renders the receiver comparison view until ctx is done
+func compare(ctx context.Context, devs []*gpsfeed.GpsDevice) error {
+	defer outPlain(_OFF + "\n") // restore terminal colors
+	manager := gpsfeed.NewManager(50, devs...)
+	states := make([]*receiverState, len(devs)) // by device index, names may repeat
+	for i := range states {
+		states[i] = &receiverState{dev: devs[i], decoder: ubx.NewDecoder()}
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- manager.Run(ctx) }()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case f, ok := <-manager.Frames:
+			if !ok {
+				return <-errc
+			}
+			if f.Index >= 0 && f.Index < len(states) {
+				states[f.Index].update(f)
+			}
+		case <-ticker.C:
+			out(renderCompare(devs, states, manager.Health()))
+		}
+	}
+}
+
+// update applies a tagged frame to the receiver state
+func (st *receiverState) update(f gpsfeed.Frame) {
+	var sentences []nmeanano.Sentence
+	switch f.Kind {
+	case gpsfeed.FrameNMEA:
+		if s, err := st.dev.Parse(f.Text()); err == nil {
+			sentences = append(sentences, s)
+		}
+	case gpsfeed.FrameUBX:
+		if frame, err := ubx.ParseFrame(f.Data); err == nil {
+			sentences, _ = st.decoder.Decode(frame)
+		}
+	}
+	for _, s := range sentences {
+		if fix, ok := st.epochs.Add(s, f.Time); ok {
+			st.fix = fix
+			if fix.Time.Valid {
+				st.fixes = append(st.fixes, fix)
+				if len(st.fixes) > _compareHistory {
+					st.fixes = st.fixes[1:]
+				}
+			}
+		}
+	}
+}
+
+// renderCompare ...
+func renderCompare(devs []*gpsfeed.GpsDevice, states []*receiverState, health []gpsfeed.Health) string {
+	var b strings.Builder
+	fmt.Fprint(&b, _cleanNewline)
+	fmt.Fprintf(&b, "\n\n\n\n\n\n")
+	fmt.Fprint(&b, _sectionLine)
+	for i, h := range health {
+		st := states[h.Index]
+		state := _ok
+		if !h.Responsive || !h.DataValid {
+			state = _alert
+		}
+		fmt.Fprintf(&b, "RECEIVER [%d]         : %s%s%s %s\n", i, _BLUE, h.Device, _OFF, state)
+		fmt.Fprintf(&b, " + Health            : Responsive %s%v%s DataValid %s%v%s Errors %s%v%s Frames %s%v%s Rate %s%.1f%s [1/s]\n", _BLUE, h.Responsive, _OFF, _BLUE, h.DataValid, _OFF, _BLUE, h.ErrCount, _OFF, _BLUE, h.Sentences, _OFF, _BLUE, h.Rate, _OFF)
+		fmt.Fprintf(&b, " + Position          : %s%.9f %.9f%s Altitude %s%.1f%s [meter] Valid %s%v%s Sat's %s%v%s Epoch %s%s%s\n", _BLUE, st.fix.Latitude, st.fix.Longitude, _OFF, _BLUE, st.fix.Altitude, _OFF, _BLUE, st.fix.Valid, _OFF, _BLUE, st.fix.Used, _OFF, _BLUE, st.fix.Time, _OFF)
+	}
+	fmt.Fprint(&b, _sectionLine)
+	for i := 0; i < len(devs); i++ {
+		for j := i + 1; j < len(devs); j++ {
+			a, c, ok := pairFixes(states[i], states[j])
+			if !ok {
+				fmt.Fprintf(&b, "DELTA [%d] <-> [%d]    : %s [no common epoch]\n", i, j, _defaultsShort)
+				continue
+			}
+			d := dist(a.Latitude, a.Longitude, 0, c.Latitude, c.Longitude, 0)
+			fmt.Fprintf(&b, "DELTA [%d] <-> [%d]    : Horizontal %s%.2f%s [meter] Altitude %s%.2f%s [meter] Epoch %s%s%s\n", i, j, _CYAN, d, _OFF, _CYAN, a.Altitude-c.Altitude, _OFF, _BLUE, a.Time, _OFF)
+		}
+	}
+	fmt.Fprint(&b, _sectionLine)
+	return b.String()
+}
+
+// pairFixes returns the latest fixes with position of both receivers whose epoch times match within
+// _compareTolerance, receivers report at different phases and a moving platform travels in between
+func pairFixes(a, c *receiverState) (epoch.Fix, epoch.Fix, bool) {
+	for i := len(a.fixes) - 1; i >= 0; i-- {
+		if !hasPosition(a.fixes[i]) {
+			continue
+		}
+		for j := len(c.fixes) - 1; j >= 0; j-- {
+			if hasPosition(c.fixes[j]) && epochDelta(a.fixes[i].Time, c.fixes[j].Time) <= _compareTolerance {
+				return a.fixes[i], c.fixes[j], true
+			}
+		}
+	}
+	return epoch.Fix{}, epoch.Fix{}, false
+}
+
+// epochDelta returns the difference of two utc times of day, across midnight
+func epochDelta(a, c nmeanano.Time) time.Duration {
+	d := timeOfDay(a) - timeOfDay(c)
+	if d < 0 {
+		d = -d
+	}
+	if d > 12*time.Hour {
+		d = 24*time.Hour - d
+	}
+	return d
+}
+
+// timeOfDay ...
+func timeOfDay(t nmeanano.Time) time.Duration {
+	return time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute + time.Duration(t.Second)*time.Second + time.Duration(t.Millisecond)*time.Millisecond
+}
+
+// hasPosition ...
+func hasPosition(f epoch.Fix) bool {
+	return f.Latitude != 0 || f.Longitude != 0
+}
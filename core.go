@@ -1,54 +1,163 @@
 package gpsinfo
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"paepcke.de/gpsinfo/gpsfeed"
+	"paepcke.de/gpsinfo/nmea2k"
+	"paepcke.de/gpsinfo/nmeanano"
+	"paepcke.de/gpsinfo/rtcm3"
+	"paepcke.de/gpsinfo/ubx"
 )
 
 // const
 const (
-	ecoSleep     = time.Duration(90 * time.Millisecond)
-	displaySleep = time.Duration(ecoSleep / 2)
-	maxDiff      = time.Duration(2 * time.Second)
+	maxDiff = time.Duration(2 * time.Second)
 )
 
-// debug ...
-func debug(dev *gpsfeed.GpsDevice) {
-	// setup
-	channelOut := make(chan string, 10)
-	channelGpsFrames := make(chan string, 50)
-
+// debug runs the device feed, builder and display until ctx is done, reopens the device after feed loss
+func debug(ctx context.Context, dev *gpsfeed.GpsDevice) error {
+	defer outPlain(_OFF + "\n") // restore terminal colors
 	for {
-		// spin up background bufio sentence fetcher/filter process
-		go func() {
-			dev.Open()
-			for dev.Feed.Scan() {
-				sentence := dev.Feed.Text()
-				dev.Responsive.Store(true)
-				l := len(sentence)
-				if l > 15 && l < 256 {
-					if sentence[0] == '$' {
-						dev.DataValid.Store(true)
-						channelGpsFrames <- sentence
+		// setup
+		if err := dev.OpenContext(ctx); err != nil {
+			return err
+		}
+		channelOut := make(chan string, 10)
+		channelGpsFrames := make(chan nmeanano.Sentence, 50)
+		stats := &feedStats{}
+
+		// spin up background bufio sentence fetcher/filter process, reads the complete feed
+		go feed(dev, stats, channelGpsFrames)
+
+		// spin up background Display outout handler
+		done := display(channelOut)
+
+		// builder loop, returns once the feed ends
+		build(dev, stats, channelGpsFrames, channelOut, newThrottle(RenderRate))
+		close(channelOut)
+		<-done
+		dev.Close()
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// feed parses the complete device feed into sentences, the channel is closed once the feed ends
+func feed(dev *gpsfeed.GpsDevice, stats *feedStats, channelGpsFrames chan nmeanano.Sentence) {
+	defer close(channelGpsFrames)
+	decoder := ubx.NewDecoder()
+	corrections := &rtcm3.Stats{}
+	for dev.Feed.Scan() {
+		dev.Responsive.Store(true)
+		token := dev.Feed.Bytes()
+		switch gpsfeed.KindOf(token) {
+		case gpsfeed.FrameRTCM3:
+			if frame, err := rtcm3.ParseFrame(token); err == nil {
+				dev.RecordType(rtcm3.TalkerRTCM, strconv.Itoa(frame.Type), gpsfeed.OutcomeOK, time.Now())
+				corrections.Add(frame, time.Now())
+				stats.send(channelGpsFrames, corrections.Status())
+			}
+			continue
+		case gpsfeed.FrameUBX:
+			if frame, err := ubx.ParseFrame(token); err == nil {
+				dev.RecordType("UBX", fmt.Sprintf("%02X-%02X", frame.Class, frame.ID), gpsfeed.OutcomeOK, time.Now())
+				dev.DataValid.Store(true)
+				sentences, _ := decoder.Decode(frame)
+				for _, s := range sentences {
+					stats.send(channelGpsFrames, s)
+				}
+			}
+			continue
+		}
+		sentence := dev.Feed.Text()
+		l := len(sentence)
+		if l > 15 && l < 256 {
+			if sentence[0] == '$' {
+				dev.DataValid.Store(true)
+				if dev.CheckSum(sentence) {
+					s, err := dev.Parse(sentence)
+					dev.Record(sentence, parseOutcome(err), time.Now())
+					if err == nil {
+						stats.send(channelGpsFrames, s)
 					}
 				}
-				time.Sleep(ecoSleep)
 			}
-			close(channelGpsFrames)
-		}()
+		}
+	}
+}
 
-		// spin up background Display outout handler
-		go func() {
-			for s := range channelOut {
-				out(s)
+// parseOutcome classifies a parse result for the device statistics
+func parseOutcome(err error) gpsfeed.Outcome {
+	switch {
+	case err == nil:
+		return gpsfeed.OutcomeOK
+	case errors.Is(err, nmeanano.ErrUnsupported):
+		return gpsfeed.OutcomeUnsupported
+	}
+	return gpsfeed.OutcomeParse
+}
+
+// debugN2K runs the can frame feed, builder and display until the source ends or ctx is done
+func debugN2K(ctx context.Context, source string) error {
+	defer outPlain(_OFF + "\n") // restore terminal colors
+
+	// setup
+	channelOut := make(chan string, 10)
+	channelGpsFrames := make(chan nmeanano.Sentence, 50)
+	stats := &feedStats{}
+	reader, err := nmea2k.Open(source)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { reader.Close() }) // unblock the feed
+	defer stop()
+
+	// spin up background can frame fetcher/decoder process
+	go func() {
+		defer close(channelGpsFrames)
+		decoder := nmea2k.NewDecoder()
+		for {
+			frame, err := reader.ReadFrame()
+			if err != nil {
+				return
+			}
+			sentences, err := decoder.Decode(frame)
+			if err != nil {
+				continue
 			}
-		}()
+			for _, s := range sentences {
+				stats.send(channelGpsFrames, s)
+			}
+		}
+	}()
 
-		// builder loop
-		build(dev, channelGpsFrames, channelOut, displaySleep)
-		close(channelGpsFrames)
-		close(channelOut)
-		dev.Close()
+	// spin up background Display outout handler
+	done := display(channelOut)
+
+	// builder loop
+	build(&gpsfeed.GpsDevice{FileIO: source}, stats, channelGpsFrames, channelOut, newThrottle(RenderRate))
+	close(channelOut)
+	<-done
+	if stop() {
+		reader.Close()
 	}
+	return ctx.Err()
+}
+
+// display writes all channel messages to stdout, the returned channel is closed once channelOut got drained
+func display(channelOut chan string) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for s := range channelOut {
+			out(s)
+		}
+		close(done)
+	}()
+	return done
 }
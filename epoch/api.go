@@ -0,0 +1,72 @@
+// package epoch groups nmea sentences by their utc fix time into immutable per-epoch Fix snapshots
+package epoch
+
+// import
+import (
+	"sync"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Fix
+//
+
+// Fix is the snapshot of a single epoch, all sentences a receiver emitted for one utc fix time.
+// A Fix is never modified once returned, its slices must not be modified by the receiver.
+type Fix struct {
+	Time       nmeanano.Time       // utc fix time [RMC, GGA, GNS], invalid for epochs without timed sentences
+	Date       nmeanano.Date       // utc date [RMC]
+	Valid      bool                // position valid [RMC validity A, GGA/GNS fix quality > 0]
+	Quality    string              // fix quality [GGA]
+	Type       string              // fix type 1: none, 2: 2D, 3: 3D [GSA]
+	Latitude   float64             // decimal degree [RMC, GGA, GNS]
+	Longitude  float64             // decimal degree [RMC, GGA, GNS]
+	Altitude   float64             // meter above mean sea level [GGA, GNS]
+	Speed      float64             // knots [RMC, VTG]
+	Course     float64             // degree true [RMC, VTG]
+	PDOP       float64             // position dilution [GSA]
+	HDOP       float64             // horizontal dilution [GSA, GGA, GNS]
+	VDOP       float64             // vertical dilution [GSA]
+	Used       int64               // satellites used [GGA, GNS, GSA]
+	InView     int64               // satellites in view, all talkers [GSV]
+	Satellites []nmeanano.GSVInfo  // satellites in view, all talkers [GSV]
+	Sentences  []nmeanano.Sentence // all sentences of the epoch, in receive order
+	Opened     time.Time           // system time of the first sentence
+	Closed     time.Time           // system time the epoch got closed
+}
+
+// String returns the fix time and sentence count, eg. [12:35:19.0000] [6 sentences]
+func (f Fix) String() string { return f.string() }
+
+// Has reports if the epoch contains a sentence of the data type, eg. GGA
+func (f Fix) Has(dataType string) bool { return f.has(dataType) }
+
+//
+// Assembler
+//
+
+// Assembler bundles a sentence stream into one Fix per epoch. An epoch is closed by the first
+// sentence with a new fix time, or by one of the configurable triggers, whatever comes first.
+// Sentences without fix time [GSA, GSV, VTG, ...] belong to the open epoch.
+// An Assembler is safe for concurrent use, the zero value is ready to use.
+type Assembler struct {
+	CloseType string        // data type of the last sentence a receiver emits per epoch, eg. GLL [empty: off]
+	Idle      time.Duration // close the open epoch after no sentence for Idle [0: off]
+	MaxAge    time.Duration // close the open epoch once older than MaxAge, eg. feeds without timed sentences [0: off]
+	mu        sync.Mutex
+	cur       *building
+}
+
+// Add feeds a sentence received at now. It returns the closed epoch, if s completed one.
+func (a *Assembler) Add(s nmeanano.Sentence, now time.Time) (Fix, bool) { return a.add(s, now) }
+
+// Expire closes the open epoch, if the Idle or MaxAge trigger is due at now
+func (a *Assembler) Expire(now time.Time) (Fix, bool) { return a.expire(now) }
+
+// Flush closes the open epoch right away, eg. at feed end
+func (a *Assembler) Flush(now time.Time) (Fix, bool) { return a.flush(now) }
+
+// Reset drops the open epoch
+func (a *Assembler) Reset() { a.reset() }
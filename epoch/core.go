@@ -0,0 +1,174 @@
+// package epoch ...
+package epoch
+
+// import
+import (
+	"strconv"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+// building is the open epoch
+type building struct {
+	fix    Fix
+	seen   time.Time        // last sentence
+	inView map[string]int64 // satellites in view per talker
+	used   int64            // satellites listed in GSA, all talkers
+	hasUse bool             // satellites used set by GGA or GNS
+}
+
+//
+// Assembler
+//
+
+// add ...
+func (a *Assembler) add(s nmeanano.Sentence, now time.Time) (fix Fix, closed bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cur != nil && a.idle(now) {
+		fix, closed = a.close(now)
+	}
+	if t, ok := timeOf(s); ok && a.cur != nil && a.cur.fix.Time.Valid && t != a.cur.fix.Time {
+		fix, closed = a.close(now) // a timed sentence of the next epoch, the idle epoch got closed before at most
+	}
+	if a.cur == nil {
+		a.cur = &building{fix: Fix{Opened: now}, inView: make(map[string]int64)}
+	}
+	a.cur.apply(s)
+	a.cur.seen = now
+	if closed {
+		return fix, true
+	}
+	if s.DataType() == a.CloseType || (a.MaxAge > 0 && now.Sub(a.cur.fix.Opened) >= a.MaxAge) {
+		return a.close(now)
+	}
+	return Fix{}, false
+}
+
+// expire ...
+func (a *Assembler) expire(now time.Time) (Fix, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cur == nil {
+		return Fix{}, false
+	}
+	if a.idle(now) || (a.MaxAge > 0 && now.Sub(a.cur.fix.Opened) >= a.MaxAge) {
+		return a.close(now)
+	}
+	return Fix{}, false
+}
+
+// flush ...
+func (a *Assembler) flush(now time.Time) (Fix, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cur == nil {
+		return Fix{}, false
+	}
+	return a.close(now)
+}
+
+// reset ...
+func (a *Assembler) reset() {
+	a.mu.Lock()
+	a.cur = nil
+	a.mu.Unlock()
+}
+
+// idle reports if the Idle trigger is due, caller holds the lock
+func (a *Assembler) idle(now time.Time) bool {
+	return a.Idle > 0 && now.Sub(a.cur.seen) >= a.Idle
+}
+
+// close completes the open epoch, caller holds the lock
+func (a *Assembler) close(now time.Time) (Fix, bool) {
+	b := a.cur
+	a.cur = nil
+	for _, n := range b.inView {
+		b.fix.InView += n
+	}
+	if !b.hasUse {
+		b.fix.Used = b.used
+	}
+	b.fix.Closed = now
+	return b.fix, true
+}
+
+//
+// Epoch
+//
+
+// apply merges a sentence into the open epoch
+func (b *building) apply(s nmeanano.Sentence) {
+	f := &b.fix
+	f.Sentences = append(f.Sentences, s)
+	if t, ok := timeOf(s); ok {
+		f.Time = t
+	}
+	switch v := s.(type) {
+	case nmeanano.RMC:
+		f.Date = v.Date
+		f.Valid = v.Validity == nmeanano.ValidRMC
+		f.Latitude, f.Longitude = v.Latitude, v.Longitude
+		f.Speed, f.Course = v.Speed, v.Course
+	case nmeanano.GGA:
+		f.Quality = v.FixQuality
+		f.Valid = f.Valid || (v.FixQuality != "" && v.FixQuality != nmeanano.Invalid)
+		f.Latitude, f.Longitude, f.Altitude = v.Latitude, v.Longitude, v.Altitude
+		f.Used, b.hasUse = v.NumSatellites, true
+		if f.HDOP == 0 {
+			f.HDOP = v.HDOP
+		}
+	case nmeanano.GNS:
+		f.Latitude, f.Longitude, f.Altitude = v.Latitude, v.Longitude, v.Altitude
+		if !b.hasUse {
+			f.Used, b.hasUse = v.SVs, true
+		}
+		if f.HDOP == 0 {
+			f.HDOP = v.HDOP
+		}
+	case nmeanano.GSA:
+		f.Type = v.FixType
+		f.PDOP, f.HDOP, f.VDOP = v.PDOP, v.HDOP, v.VDOP
+		for _, sv := range v.SV {
+			if sv != "" {
+				b.used++
+			}
+		}
+	case nmeanano.GSV:
+		b.inView[v.Talker] = v.NumberSVsInView
+		f.Satellites = append(f.Satellites, v.Info...)
+	case nmeanano.VTG:
+		f.Speed, f.Course = v.GroundSpeedKnots, v.TrueTrack
+	}
+}
+
+// has ...
+func (f Fix) has(dataType string) bool {
+	for _, s := range f.Sentences {
+		if s.DataType() == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// timeOf returns the utc fix time of a sentence, if any
+func timeOf(s nmeanano.Sentence) (nmeanano.Time, bool) {
+	var t nmeanano.Time
+	switch v := s.(type) {
+	case nmeanano.RMC:
+		t = v.Time
+	case nmeanano.GGA:
+		t = v.Time
+	case nmeanano.GNS:
+		t = v.Time
+	}
+	return t, t.Valid
+}
+
+// string ...
+func (f Fix) string() string {
+	return "[" + f.Time.String() + "] [" + strconv.Itoa(len(f.Sentences)) + " sentences]"
+}
@@ -0,0 +1,160 @@
+package epoch
+
+import (
+	"testing"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+// _epoch is one receiver cycle at 12:35:19, terminated by VTG
+var _epoch = []string{
+	"GPRMC,123519.00,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W",
+	"GPGGA,123519.00,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,",
+	"GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1",
+	"GPGSV,1,1,02,04,40,083,46,05,17,308,41",
+	"GLGSV,1,1,01,65,20,120,38",
+	"GPVTG,084.4,T,,M,022.4,N,041.5,K,A",
+}
+
+// _next is the first sentence of the following epoch
+const _next = "GPRMC,123520.00,A,4807.040,N,01131.002,E,022.4,084.4,230394,003.1,W"
+
+// sentence parses s, adds start char and checksum
+func sentence(t *testing.T, s string) nmeanano.Sentence {
+	t.Helper()
+	v, err := nmeanano.Parse("$" + s + "*" + nmeanano.Checksum(s))
+	if err != nil {
+		t.Fatalf("parse %s: %v", s, err)
+	}
+	return v
+}
+
+// feed adds the sentences one per millisecond from start, returns the closed epochs
+func feed(t *testing.T, a *Assembler, start time.Time, lines ...string) (fixes []Fix) {
+	t.Helper()
+	for i, s := range lines {
+		if fix, ok := a.Add(sentence(t, s), start.Add(time.Duration(i)*time.Millisecond)); ok {
+			fixes = append(fixes, fix)
+		}
+	}
+	return fixes
+}
+
+// TestClose covers the close triggers, each must close the epoch exactly once
+func TestClose(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 35, 19, 0, time.UTC)
+	tests := []struct {
+		name  string
+		a     *Assembler
+		lines []string
+		close func(a *Assembler) (Fix, bool) // trigger after the lines [nil: none]
+		want  int                            // sentences of the closed epoch [0: still open]
+	}{
+		{name: "open", lines: _epoch},
+		{name: "time change", lines: append(_epoch[:2:2], _next), want: 2},
+		{name: "same time", lines: _epoch[:2]},
+		{name: "close type", a: &Assembler{CloseType: nmeanano.TypeVTG}, lines: _epoch, want: 6},
+		{name: "close type early", a: &Assembler{CloseType: nmeanano.TypeGSA}, lines: _epoch[:3], want: 3},
+		{name: "flush", lines: _epoch[:4], close: func(a *Assembler) (Fix, bool) { return a.Flush(start.Add(time.Second)) }, want: 4},
+		{name: "flush empty", close: func(a *Assembler) (Fix, bool) { return a.Flush(start) }},
+		{name: "expire off", lines: _epoch, close: func(a *Assembler) (Fix, bool) { return a.Expire(start.Add(time.Hour)) }},
+		{name: "expire idle", a: &Assembler{Idle: 100 * time.Millisecond}, lines: _epoch,
+			close: func(a *Assembler) (Fix, bool) { return a.Expire(start.Add(105 * time.Millisecond)) }, want: 6},
+		{name: "expire idle early", a: &Assembler{Idle: 100 * time.Millisecond}, lines: _epoch,
+			close: func(a *Assembler) (Fix, bool) { return a.Expire(start.Add(104 * time.Millisecond)) }},
+		{name: "expire max age", a: &Assembler{MaxAge: time.Second}, lines: _epoch,
+			close: func(a *Assembler) (Fix, bool) { return a.Expire(start.Add(time.Second)) }, want: 6},
+		{name: "max age on add", a: &Assembler{MaxAge: 3 * time.Millisecond}, lines: _epoch, want: 4},
+		{name: "idle on add", a: &Assembler{Idle: 100 * time.Millisecond}, lines: _epoch[:2],
+			close: func(a *Assembler) (Fix, bool) {
+				return a.Add(sentence(t, _epoch[2]), start.Add(time.Second))
+			}, want: 2},
+	}
+	for _, tt := range tests {
+		if tt.a == nil {
+			tt.a = &Assembler{}
+		}
+		fixes := feed(t, tt.a, start, tt.lines...)
+		if tt.close != nil {
+			if fix, ok := tt.close(tt.a); ok {
+				fixes = append(fixes, fix)
+			}
+		}
+		switch {
+		case tt.want == 0 && len(fixes) != 0:
+			t.Errorf("%s: closed %v, want open", tt.name, fixes)
+		case tt.want == 0:
+		case len(fixes) != 1:
+			t.Errorf("%s: %d epochs closed, want 1", tt.name, len(fixes))
+		case len(fixes[0].Sentences) != tt.want:
+			t.Errorf("%s: closed %v, want %d sentences", tt.name, fixes[0], tt.want)
+		}
+	}
+}
+
+// TestFix checks the merged epoch values
+func TestFix(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 35, 19, 0, time.UTC)
+	a := Assembler{CloseType: nmeanano.TypeVTG}
+	fixes := feed(t, &a, start, _epoch...)
+	if len(fixes) != 1 {
+		t.Fatalf("%d epochs closed, want 1", len(fixes))
+	}
+	f := fixes[0]
+	want := nmeanano.Time{Valid: true, Hour: 12, Minute: 35, Second: 19}
+	if f.Time != want || !f.Valid || f.Quality != "1" || f.Type != "3" {
+		t.Errorf("time, validity = %v %v %q %q", f.Time, f.Valid, f.Quality, f.Type)
+	}
+	if f.Used != 8 || f.InView != 3 || len(f.Satellites) != 3 || f.HDOP != 1.3 || f.Altitude != 545.4 {
+		t.Errorf("used %d, in view %d, satellites %d, hdop %v, altitude %v", f.Used, f.InView, len(f.Satellites), f.HDOP, f.Altitude)
+	}
+	if !f.Opened.Equal(start) || !f.Closed.Equal(start.Add(5*time.Millisecond)) || !f.Has(nmeanano.TypeGSA) || f.Has("GNS") {
+		t.Errorf("opened %v, closed %v, sentences %v", f.Opened, f.Closed, f)
+	}
+}
+
+// TestFixImmutable checks that later epochs never touch the slices of a returned Fix
+func TestFixImmutable(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 35, 19, 0, time.UTC)
+	a := Assembler{CloseType: nmeanano.TypeVTG}
+	fixes := feed(t, &a, start, _epoch...)
+	if len(fixes) != 1 {
+		t.Fatalf("%d epochs closed, want 1", len(fixes))
+	}
+	f := fixes[0]
+	sentences, satellites := f.Sentences[:cap(f.Sentences)], f.Satellites[:cap(f.Satellites)]
+	first, sat := sentences[0].String(), satellites[0]
+	feed(t, &a, start.Add(time.Second), _next, "GPGSV,1,1,01,12,10,010,30", "GPGSA,A,2,12,,,,,,,,,,,,3.0,2.0,2.2")
+	if _, ok := a.Flush(start.Add(2 * time.Second)); !ok {
+		t.Fatal("Flush: no open epoch")
+	}
+	if len(f.Sentences) != 6 || sentences[0].String() != first || satellites[0] != sat {
+		t.Errorf("returned fix changed: %v", f)
+	}
+	for i := len(f.Sentences); i < len(sentences); i++ {
+		if sentences[i] != nil {
+			t.Errorf("sentence %d of the next epoch in the returned fix backing array: %v", i, sentences[i])
+		}
+	}
+	for i := len(f.Satellites); i < len(satellites); i++ {
+		if satellites[i] != (nmeanano.GSVInfo{}) {
+			t.Errorf("satellite %d of the next epoch in the returned fix backing array: %+v", i, satellites[i])
+		}
+	}
+}
+
+// TestReset checks that Reset drops the open epoch
+func TestReset(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 35, 19, 0, time.UTC)
+	a := Assembler{CloseType: nmeanano.TypeVTG}
+	feed(t, &a, start, _epoch[:4]...)
+	a.Reset()
+	if fix, ok := a.Flush(start); ok {
+		t.Errorf("Flush after Reset = %v", fix)
+	}
+	fixes := feed(t, &a, start.Add(time.Second), _epoch[1:]...)
+	if len(fixes) != 1 || len(fixes[0].Sentences) != 5 || fixes[0].Has(nmeanano.TypeRMC) || !fixes[0].Opened.Equal(start.Add(time.Second)) {
+		t.Errorf("epoch after Reset = %v", fixes)
+	}
+}
@@ -4,9 +4,17 @@ package gpsfeed
 // import
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
 )
 
 //
@@ -18,24 +26,484 @@ const DeviceTimeout = _deviceDataValidTimeout + 1 // wait before try to access d
 
 // GpsDevice holds all the feed, locks and handles for a specifc gps input device dongle
 type GpsDevice struct {
-	FileIO     string         // file name, eg. /dev/gps0
+	FileIO     string         // file name or network url, eg. /dev/gps0, tcp://host:10110
 	Feed       *bufio.Scanner // line feed
 	ErrCount   atomic.Uint64  // global err counter
 	InitDone   atomic.Bool    // initial time sync done
 	Responsive atomic.Bool    // feed state [responsive/unresponsive]
 	DataValid  atomic.Bool    // feed state [datavalid/invalidutput]]
+	Garbage    atomic.Uint64  // bytes skipped by the framing layer
 	Dog        atomic.Bool    // watchdog state
 	Lock       sync.Mutex     // global device lock
-	Handle     *os.File       // file handle
+	Source     Source         // input source [nil: derived from FileIO on Open]
 	Global     sync.WaitGroup // global state
+	Serial     *SerialConfig  // serial line settings, applied on every open [nil: keep port settings]
+	Events     chan Event     // device events, sent non-blocking [nil: Handler]
+	Handler    Handler        // device events, called synchronously [nil: DefaultHandler, unless Events is set]
+
+	ResponsiveTimeout time.Duration // unresponsive watchdog, trips when nothing is emitted [0: 5s]
+	DataValidTimeout  time.Duration // data valid watchdog, trips when no valid data is emitted [0: 6s]
+
+	Reconnect       *ReconnectPolicy // retries after a failed open [nil: fixed DeviceTimeout delay, never give up]
+	RequireChecksum bool             // CheckSum rejects sentences without checksum
+	Profile         *Profile         // receiver configuration, applied and verified after every open [nil: keep]
+
+	stop      chan struct{}  // closed on device close, stops the watchdogs
+	watchdogs sync.WaitGroup // watchdogs of the current open
+	stats     sentenceStats  // per talker+type counters
+	waiters   waiters        // pending requests
+	writeLock sync.Mutex     // serializes Send, source set and close
+	serial    SerialConfig   // serial settings of the current open, Serial at the profile baud rate
+	baud      atomic.Int64   // baud rate switched by the profile [0: Serial.Baud]
+	baudFail  atomic.Int64   // profile baud rate without valid data, not switched to again
+	attempts  atomic.Int64   // failed opens in a row
+	failed    atomic.Int64   // first failed open in a row [unix nano]
+	nextRetry atomic.Int64   // next open attempt [unix nano, 0: none pending]
 }
 
 // Open ...
-func (dev *GpsDevice) Open() { openDev(dev) }
+func (dev *GpsDevice) Open() { openDev(context.Background(), dev) }
+
+// OpenContext opens the device, retries until success or ctx is done. Once open, cancelling ctx
+// closes the source and stops the watchdogs, the caller still has to Close the device.
+func (dev *GpsDevice) OpenContext(ctx context.Context) error { return openDev(ctx, dev) }
 
 // Close ...
 func (dev *GpsDevice) Close() { closeDev(dev) }
 
+//
+// Reconnect
+//
+
+// ErrGiveUp is returned by OpenContext once the reconnect policy limits are exhausted
+var ErrGiveUp = errors.New("reconnect attempts exhausted")
+
+// ReconnectPolicy is an exponential backoff with jitter for the retries after a failed open
+type ReconnectPolicy struct {
+	Initial     time.Duration // first retry delay [0: 1s]
+	Max         time.Duration // delay cap [0: 1m]
+	Multiplier  float64       // delay growth per attempt [0: 2]
+	Jitter      float64       // random delay spread, eg. 0.2 for +/-20% [0: none]
+	MaxAttempts int           // give up after n failed opens in a row [0: never]
+	MaxDuration time.Duration // give up after failing for d [0: never]
+}
+
+// Delay returns the retry delay after the failed attempt n [1..], without jitter
+func (p *ReconnectPolicy) Delay(n int) time.Duration { return p.delay(n) }
+
+// Attempts returns the number of failed opens in a row, 0 once open
+func (dev *GpsDevice) Attempts() int { return int(dev.attempts.Load()) }
+
+// NextRetry returns the time of the next open attempt, zero if none is pending
+func (dev *GpsDevice) NextRetry() time.Time { return nextRetry(dev) }
+
+//
+// Events
+//
+
+// EventKind identifies a device event
+type EventKind int
+
+// event kinds
+const (
+	EventUnresponsive EventKind = iota + 1 // responsive watchdog tripped, nothing emitted
+	EventInvalidData                       // data valid watchdog tripped, no valid data emitted
+	EventOpened                            // device source opened
+	EventClosed                            // device closed
+	EventChecksum                          // nmea sentence with checksum mismatch
+	EventReconnect                         // device source open failed, retrying
+	EventGiveUp                            // device source open failed, reconnect policy exhausted
+	EventProfile                           // receiver profile applied and verified
+	EventProfileError                      // receiver profile rejected or not verified
+	EventBaudProbe                         // AutoBaud probed a baud rate
+	EventBaudLocked                        // AutoBaud locked the serial settings onto the best rate
+	EventBaudFailed                        // AutoBaud found no valid nmea data at any rate
+)
+
+// String ...
+func (k EventKind) String() string { return eventName(k) }
+
+// Level returns the slog level of the event kind
+func (k EventKind) Level() slog.Level { return eventLevel(k) }
+
+// Event is a device state change
+type Event struct {
+	Kind     EventKind // what happened
+	Device   string    // device name
+	Time     time.Time // when
+	ErrCount uint64    // device error counter at event time
+	Err      error     // cause, if any [eg. the open error]
+	Detail   string    // context, if any [eg. the failing sentence]
+	Attempt  int       // failed opens in a row [EventReconnect, EventGiveUp]
+	Retry    time.Time // next open attempt [EventReconnect]
+	Baud     int       // baud rate [EventBaudProbe, EventBaudLocked, EventOpened with Serial]
+}
+
+// String returns the event as classic gpsfeed log line
+func (e Event) String() string { return eventString(e) }
+
+// Handler receives device events. Handle is called from the feed and watchdog goroutines, it must not block.
+type Handler interface {
+	Handle(e Event)
+}
+
+// HandlerFunc adapts a func to a Handler
+type HandlerFunc func(e Event)
+
+// Handle ...
+func (f HandlerFunc) Handle(e Event) { f(e) }
+
+// DefaultHandler receives the events of all devices without Handler and Events [nil: drop]
+var DefaultHandler Handler = StdoutHandler()
+
+// StdoutHandler prints watchdog trips and open errors on stdout, until the device error limit is reached
+func StdoutHandler() Handler { return HandlerFunc(stdoutEvent) }
+
+// SlogHandler logs all events to l [nil: slog.Default()] with the event kind level
+func SlogHandler(l *slog.Logger) Handler { return slogHandler{l: l} }
+
+// CheckSum validates an nmea sentence checksum, a mismatch is reported as EventChecksum and
+// counted in the device Stats. Sentences without checksum are valid, unless RequireChecksum is set.
+// Lower case hex, tag blocks and trailing whitespace are accepted, malformed input never panics.
+func (dev *GpsDevice) CheckSum(sentence string) bool { return checkSumEvent(dev, sentence) }
+
+// Parse parses an nmea sentence with the checksum mode of CheckSum, so both agree on every sentence
+func (dev *GpsDevice) Parse(sentence string) (nmeanano.Sentence, error) {
+	return parseSentence(dev, sentence)
+}
+
+//
+// Source
+//
+
+// Source is a reopenable gps data input, eg. a device file, network connection, pipe or memory buffer.
+// Close must unblock a pending Read, the watchdogs rely on it.
+type Source interface {
+	Open() error
+	Read(p []byte) (int, error)
+	Close() error
+	Name() string
+}
+
+// NewSource returns the source for a device name: "-" is stdin, tcp://, tcp-listen://, udp:// urls
+// are network sources, usb:VID:PID[:SERIAL] is re-resolved on every open, anything else is a device
+// or file, opened with the serial settings, if any
+func NewSource(name string, serial *SerialConfig) Source { return newSource(name, serial) }
+
+// NewStdinSource returns a source reading from stdin
+func NewStdinSource() Source { return &readerSource{name: _stdin, r: os.Stdin} }
+
+// ErrSourceEnded is returned by Open once a stdin or pipe source reached the end of its stream,
+// the device gives up right away instead of retrying
+var ErrSourceEnded = errors.New("source ended, can not reopen")
+
+// NewPipeSource returns a source for an already opened reader, eg. a pipe or command output. The reader
+// is drained in the background, Close unblocks a pending Read and a reopen resumes the stream.
+func NewPipeSource(name string, r io.Reader) Source { return &readerSource{name: name, r: r} }
+
+// NewMemorySource returns a source replaying data, every Open rewinds
+func NewMemorySource(name string, data []byte) Source { return &memorySource{name: name, data: data} }
+
+//
+// USB
+//
+
+// USBMatch selects a usb tty device by its ids, empty fields match any
+type USBMatch struct {
+	Vendor  string // vendor id, lower case hex, eg. 1546 [u-blox]
+	Product string // product id, lower case hex, eg. 01a8
+	Serial  string // usb serial number
+}
+
+// ParseUSB parses a device spec usb:VID:PID[:SERIAL], eg. usb:1546:01a8 or usb:::A12345.
+// Device names in this form are resolved via /sys/class/tty on every open [linux].
+func ParseUSB(spec string) (USBMatch, error) { return parseUSB(spec) }
+
+// Resolve returns the current device path of the first matching tty, eg. /dev/ttyACM1
+func (m USBMatch) Resolve() (string, error) { return m.resolve() }
+
+// String returns the device spec
+func (m USBMatch) String() string { return m.string() }
+
+//
+// Send
+//
+
+// Send errors
+var (
+	ErrReadOnly = errors.New("device source is read-only")
+	ErrTimeout  = errors.New("request timeout")
+	ErrNak      = errors.New("command not acknowledged")
+)
+
+// Matcher selects the response frame of a Request
+type Matcher func(token []byte) bool
+
+// MatchPrefix matches frames starting with prefix, eg. $PSRF
+func MatchPrefix(prefix string) Matcher {
+	return func(token []byte) bool { return bytes.HasPrefix(token, []byte(prefix)) }
+}
+
+// Send writes an nmea command, start char, checksum and line end are added, eg. PMTK220,100.
+// Device files are opened read-write if permitted, network sources are writable over tcp.
+func (dev *GpsDevice) Send(cmd string) error { return write(dev, []byte(formatCommand(cmd))) }
+
+// SendRaw writes raw bytes, eg. an encoded ubx frame
+func (dev *GpsDevice) SendRaw(b []byte) error { return write(dev, b) }
+
+// Request writes cmd and returns the first frame accepted by match within timeout [0: DeviceTimeout].
+// The device feed has to be consumed concurrently, eg. by gpsinfo or a Manager.
+func (dev *GpsDevice) Request(cmd []byte, match Matcher, timeout time.Duration) ([]byte, error) {
+	return request(dev, cmd, match, timeout)
+}
+
+// SendPMTK sends a mediatek command, eg. PMTK220,100, and waits for its PMTK001 ack
+func (dev *GpsDevice) SendPMTK(cmd string, timeout time.Duration) error {
+	return sendPMTK(dev, cmd, timeout)
+}
+
+// SendUBX sends a ubx message and waits for its ACK-ACK, ACK-NAK fails with ErrNak
+func (dev *GpsDevice) SendUBX(class, id byte, payload []byte, timeout time.Duration) error {
+	return sendUBX(dev, class, id, payload, timeout)
+}
+
+//
+// Profile
+//
+
+// ErrProfile is returned once the sentence mix after an apply does not match the profile
+var ErrProfile = errors.New("profile verification failed")
+
+// profile protocols
+const (
+	ProtocolPMTK = "pmtk" // mediatek [PMTK220, PMTK314, PMTK353, PMTK313/301, PMTK251]
+	ProtocolUBX  = "ubx"  // u-blox [CFG-RATE, CFG-MSG, CFG-GNSS, CFG-SBAS, CFG-PRT]
+)
+
+// Profile is a declarative receiver configuration, zero values keep the receiver settings
+type Profile struct {
+	Protocol  string   // command set [pmtk|ubx]
+	Rate      float64  // navigation epoch rate [Hz]
+	Sentences []string // enabled nmea sentence types, all other switchable types are disabled, eg. RMC, GGA
+	GNSS      []string // enabled constellations [gps, glonass, galileo, beidou, qzss], all others are disabled
+	SBAS      *bool    // sbas ranging and corrections
+	Baud      int      // serial baud rate, switched last, the source is reopened at the new rate, at Serial.Baud again without valid data
+}
+
+// ParseProfile parses a profile, eg. [protocol=ubx rate=5 sentences=RMC,GGA,GSV gnss=gps,galileo sbas=off baud=115200]
+func ParseProfile(s string) (Profile, error) { return parseProfile(s) }
+
+// String returns the profile in the notation accepted by ParseProfile
+func (p Profile) String() string { return p.string() }
+
+// Profiles holds the profiles by device name, * is the default for all other devices
+type Profiles map[string]Profile
+
+// ReadProfiles parses a profile file, one device per line: <device> <profile>, # starts a comment, eg.
+//
+//	/dev/gps0      protocol=pmtk rate=5 sentences=RMC,GGA,GSA,GSV gnss=gps,glonass sbas=on baud=57600
+//	usb:1546:01a8  protocol=ubx rate=10 sentences=RMC,GGA gnss=gps,galileo
+func ReadProfiles(r io.Reader) (Profiles, error) { return readProfiles(r) }
+
+// LoadProfiles reads the profile file at path
+func LoadProfiles(path string) (Profiles, error) { return loadProfiles(path) }
+
+// Lookup returns the profile of the device, the default profile or nil
+func (ps Profiles) Lookup(device string) *Profile { return ps.lookup(device) }
+
+// ApplyProfile configures the receiver and verifies the sentence mix that follows [a few seconds].
+// The device feed has to be consumed concurrently. A baud switch closes the source without verify,
+// the consumer reopens at the new rate, set Profile to get the profile re-applied and verified.
+func (dev *GpsDevice) ApplyProfile(ctx context.Context, p Profile) error {
+	_, err := applyProfile(ctx, dev, p, nil)
+	return err
+}
+
+//
+// Serial Port
+//
+
+// BaudRates are the rates probed by AutoBaud, in probe order
+var BaudRates = []int{4800, 9600, 19200, 38400, 57600, 115200, 230400, 460800, 921600}
+
+const _autoBaudWindow = 2 * time.Second
+
+// AutoBaud probes all BaudRates for the time window each [0: 2s], scores them by the fraction
+// of sentences with a valid checksum and locks the device serial settings onto the best one.
+// Probe results and the chosen rate are reported as EventBaudProbe, EventBaudLocked, EventBaudFailed.
+func (dev *GpsDevice) AutoBaud(window time.Duration) (int, error) {
+	return autoBaud(context.Background(), dev, window)
+}
+
+// AutoBaudContext is AutoBaud, it stops probing once ctx is done and returns its error
+func (dev *GpsDevice) AutoBaudContext(ctx context.Context, window time.Duration) (int, error) {
+	return autoBaud(ctx, dev, window)
+}
+
+//
+// Discover
+//
+
+// DiscoverPatterns are the device globs probed by Discover
+var DiscoverPatterns = []string{"/dev/ttyS*", "/dev/ttyUSB*", "/dev/ttyACM*", "/dev/gps*", "/dev/serial/by-id/*"}
+
+// Discovery is the probe result of a single device
+type Discovery struct {
+	Device  string   // device path, symlinks resolved
+	Aliases []string // symlinks to the device, eg. /dev/gps0, /dev/serial/by-id/...
+	USB     USBMatch // usb ids, if any
+	Valid   int      // nmea sentences with valid checksum
+	Total   int      // nmea sentences
+	Rate    float64  // valid sentences per second
+	Talkers []string // talker ids seen, P for proprietary
+	Model   string   // receiver model, if revealed [PMTK705, PUBX, TXT]
+	Err     error    // open error
+}
+
+// Found reports if the device emits valid nmea data
+func (d Discovery) Found() bool { return d.Valid > 0 }
+
+// Discover probes all DiscoverPatterns devices concurrently for the time window [0: 2s] with their
+// current port settings, devices reachable via several symlinks are probed once
+func Discover(ctx context.Context, window time.Duration) []Discovery { return discover(ctx, window) }
+
+//
+// Framing
+//
+
+// FrameKind identifies the protocol of a token returned by the device feed
+type FrameKind int
+
+// frame kinds
+const (
+	FrameUnknown FrameKind = iota // garbage
+	FrameNMEA                     // nmea 0183 text line [$, !, tag block]
+	FrameUBX                      // u-blox ubx binary frame
+	FrameRTCM3                    // rtcm3 correction frame
+)
+
+// SplitFrames is a bufio.SplitFunc for mixed nmea text, ubx and rtcm3 binary streams. It returns
+// nmea lines [without line end] and complete, checksum verified binary frames, garbage is skipped.
+// The device Feed uses it by default.
+func SplitFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return splitFrames(data, atEOF)
+}
+
+// KindOf returns the protocol of a token returned by SplitFrames
+func KindOf(token []byte) FrameKind { return kindOf(token) }
+
+// NewScanner returns a bufio.Scanner with SplitFrames framing, immune to overlong lines
+func NewScanner(r io.Reader) *bufio.Scanner { return newScanner(r, nil, nil) }
+
+// Demux delivers the frames of a feed on separate, typed channels. It never blocks on a consumer:
+// frames of a full channel are dropped and counted, a nil channel skips its stream.
+type Demux struct {
+	NMEA         chan string   // nmea text lines
+	UBX          chan []byte   // raw ubx frames
+	RTCM3        chan []byte   // raw rtcm3 frames
+	DroppedNMEA  atomic.Uint64 // nmea lines dropped on a full channel
+	DroppedUBX   atomic.Uint64 // ubx frames dropped on a full channel
+	DroppedRTCM3 atomic.Uint64 // rtcm3 frames dropped on a full channel
+}
+
+// NewDemux returns a Demux with channels of the given buffer size, set unused ones to nil before Run
+func NewDemux(size int) *Demux { return newDemux(size) }
+
+// Run dispatches all frames of the device feed until it ends, closes all channels and returns the feed error
+func (d *Demux) Run(dev *GpsDevice) error { return d.run(dev.Feed, dev) }
+
+// RunReader dispatches all frames read from r until it ends, closes all channels and returns the read error
+func (d *Demux) RunReader(r io.Reader) error { return d.run(newScanner(r, nil, nil), nil) }
+
+//
+// Manager
+//
+
+// Frame is a single nmea line, ubx or rtcm3 frame, tagged with its source device
+type Frame struct {
+	Device string    // source device name
+	Index  int       // device index in the Manager, names may repeat
+	Kind   FrameKind // protocol
+	Data   []byte    // raw line or frame
+	Time   time.Time // receive time
+}
+
+// Text returns the frame data as string, eg. the nmea sentence
+func (f Frame) Text() string { return string(f.Data) }
+
+// Health is the state of a single receiver of a Manager
+type Health struct {
+	Device     string    // device name
+	Index      int       // device index in the Manager
+	Responsive bool      // emitted anything within its responsive timeout
+	DataValid  bool      // emitted valid data within its data valid timeout
+	ErrCount   uint64    // device error counter
+	Sentences  uint64    // frames received
+	Rate       float64   // frames per second
+	Last       time.Time // last frame received
+}
+
+// Manager runs several receivers side by side and merges their tagged frames into one channel
+type Manager struct {
+	Frames    chan Frame // tagged frames of all devices, closed once Run returns
+	receivers []*receiver
+}
+
+// NewManager returns a Manager for the devices, Frames is buffered with size
+func NewManager(size int, devs ...*GpsDevice) *Manager { return newManager(size, devs) }
+
+// Run opens all devices concurrently and feeds Frames until ctx is done, devices are reopened after feed loss
+func (m *Manager) Run(ctx context.Context) error { return m.run(ctx) }
+
+// Health returns the current state of all receivers, in device order
+func (m *Manager) Health() []Health { return m.health() }
+
+//
+// Statistics
+//
+
+// Outcome classifies a counted sentence
+type Outcome int
+
+// outcomes
+const (
+	OutcomeOK          Outcome = iota // valid, parsed if the consumer parses
+	OutcomeChecksum                   // checksum mismatch
+	OutcomeParse                      // parse error
+	OutcomeUnsupported                // no parser for the type
+)
+
+// SentenceStats are the counters of a single talker+type of a device
+type SentenceStats struct {
+	Talker      string        // talker id, eg. GP, P for proprietary, UBX for ubx binary frames
+	Type        string        // sentence type, eg. RMC, OTHER for failed sentences of unseen talker+types and beyond the cap
+	Count       uint64        // sentences seen, incl. failed
+	Checksum    uint64        // checksum mismatches
+	Parse       uint64        // parse errors
+	Unsupported uint64        // no parser for the type
+	Rate        float64       // sentences per second
+	Interval    time.Duration // average interval
+	Jitter      time.Duration // average interval deviation
+	Last        time.Time     // last seen
+}
+
+// Record counts an nmea sentence received at t. CheckSum records mismatches on its own.
+func (dev *GpsDevice) Record(sentence string, o Outcome, t time.Time) {
+	talker, typ := addressOf(sentence)
+	dev.stats.record(talker, typ, o, t)
+}
+
+// RecordType counts a sentence or binary frame of talker+type received at t
+func (dev *GpsDevice) RecordType(talker, typ string, o Outcome, t time.Time) {
+	dev.stats.record(talker, typ, o, t)
+}
+
+// Stats returns a snapshot of the per talker+type counters, ordered by talker and type
+func (dev *GpsDevice) Stats() []SentenceStats { return dev.stats.snapshot() }
+
+// ResetStats drops all per talker+type counters
+func (dev *GpsDevice) ResetStats() { dev.stats.reset() }
+
 //
 // Error Handling
 //
@@ -55,7 +523,7 @@ func (dev *GpsDevice) CheckErrAdd() bool { return checkErrCount(dev) }
 // GetDeviceName ...
 func GetDeviceName(arg, old string) string { return getDevice(arg, old) }
 
-// CheckSumValid validates an NMEA sentence checksum
+// CheckSumValid validates an NMEA sentence checksum, sentences without checksum are invalid
 func CheckSumValid(sentence string) bool { return checkSumValid(sentence) }
 
 // CheckSumTag calculates the NMEA sentence CheckSum and returns an tag string [ok|fail]
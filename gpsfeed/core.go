@@ -3,10 +3,10 @@ package gpsfeed
 
 // import
 import (
-	"bufio"
-	"strings"
-	"sync"
+	"context"
 	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
 )
 
 const (
@@ -20,27 +20,72 @@ const (
 // Device
 //
 
-// openDev sets the global lock and creates the device buffered io scanner
-func openDev(dev *GpsDevice) {
-	var ok bool
-	watchdogs.Wait() // expire all old global watchdog locks
+// openDev sets the global lock and creates the device buffered io scanner, until ctx is done
+func openDev(ctx context.Context, dev *GpsDevice) error {
 	dev.Lock.Lock()
+	dev.watchdogs.Wait() // expire the watchdogs of the previous open
+	dev.writeLock.Lock() // Send reads source and name
+	if dev.Source == nil {
+		if dev.Serial == nil && dev.Profile != nil && dev.Profile.Baud > 0 {
+			dev.Serial = &SerialConfig{Raw: true} // keep the port rate until the profile switches it
+		}
+		var serial *SerialConfig
+		if dev.Serial != nil {
+			serial = &dev.serial // Serial at the profile baud rate, see switchBaud
+		}
+		dev.Source = newSource(dev.FileIO, serial)
+	}
+	if dev.FileIO == "" {
+		dev.FileIO = dev.Source.Name()
+	}
+	dev.writeLock.Unlock()
+	if dev.Serial != nil {
+		dev.serial = *dev.Serial
+		if baud := dev.baud.Load(); baud > 0 {
+			dev.serial.Baud = int(baud)
+		}
+	}
 	for {
-		if dev.Handle, ok = getHandle(dev); !ok {
+		if err := ctx.Err(); err != nil {
+			dev.Lock.Unlock()
+			return err
+		}
+		ok, err := getHandle(ctx, dev)
+		if err != nil {
+			dev.Lock.Unlock()
+			return err
+		}
+		if !ok {
 			continue
 		}
-		dev.Feed = bufio.NewScanner(dev.Handle)
+		stop := make(chan struct{})
+		dev.stop = stop
+		dev.Feed = newScanner(dev.Source, func(n int) { dev.Garbage.Add(uint64(n)) }, dev.waiters.dispatch)
 		dev.Dog.Store(true)
-		go watchdog(dev)
-		return
+		watchdog(ctx, dev, stop)
+		if dev.Profile != nil {
+			profile(ctx, dev, *dev.Profile, stop)
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				dev.Source.Close() // unblock the feed
+			case <-stop:
+			}
+		}()
+		return nil
 	}
 }
 
-// closeDev do close/realease all locks and resets the watchdog
+// closeDev do close/realease all locks and stops the watchdogs
 func closeDev(dev *GpsDevice) {
 	dev.Dog.Store(false)
 	dev.ErrCount.Store(0)
-	dev.Handle.Close()
+	close(dev.stop)
+	dev.writeLock.Lock() // no Send half way through the close
+	dev.Source.Close()
+	dev.writeLock.Unlock()
+	emit(dev, Event{Kind: EventClosed})
 	dev.Lock.Unlock()
 }
 
@@ -48,13 +93,12 @@ func closeDev(dev *GpsDevice) {
 // Watchdogs
 //
 
-// ...
-var watchdogs sync.WaitGroup
-
-// watchdog background tasks will timeout and close the device file handle and terminate bufio scanner if needed
-func watchdog(dev *GpsDevice) {
-	watchdogs.Add(2)
+// watchdog background tasks will timeout and close the device file handle and terminate bufio scanner if needed,
+// they exit on device close or when ctx is done
+func watchdog(ctx context.Context, dev *GpsDevice, stop chan struct{}) {
+	dev.watchdogs.Add(2)
 	go func() {
+		defer dev.watchdogs.Done()
 		dev.DataValid.Store(true)
 		oldErr := dev.ErrCount.Load()
 		for dev.DataValid.Load() {
@@ -62,17 +106,18 @@ func watchdog(dev *GpsDevice) {
 				break
 			}
 			dev.DataValid.Store(false) // re-arm deviceDataValid watchdog
-			time.Sleep(_deviceDataValidTimeout * time.Second)
+			if !sleep(ctx, stop, dev.dataValidTimeout()) {
+				return
+			}
 		}
 		if dev.Dog.Swap(false) { // we are fist to trigger ?
-			dev.Handle.Close()
-			if dev.CheckErrAdd() {
-				out(_errDeviceInvalidData + " [" + dev.FileIO + "] [" + dev.GetErr() + "]")
-			}
+			dev.Source.Close()
+			baudFallback(dev)
+			trip(dev, EventInvalidData)
 		}
-		watchdogs.Done()
 	}()
 	go func() {
+		defer dev.watchdogs.Done()
 		dev.Responsive.Store(true)
 		oldErr := dev.ErrCount.Load()
 		for dev.Responsive.Load() {
@@ -80,18 +125,95 @@ func watchdog(dev *GpsDevice) {
 				break
 			}
 			dev.Responsive.Store(false) // re-arm deviceResponsive watchdog
-			time.Sleep(_deviceResponsiveTimeout * time.Second)
+			if !sleep(ctx, stop, dev.responsiveTimeout()) {
+				return
+			}
 		}
 		if dev.Dog.Swap(false) { // we are fist to trigger ?
-			dev.Handle.Close()
-			if dev.CheckErrAdd() {
-				out(_errDeviceUnresponsive + " [" + dev.FileIO + "] [" + dev.GetErr() + "]")
-			}
+			dev.Source.Close()
+			trip(dev, EventUnresponsive)
 		}
-		watchdogs.Done()
 	}()
 }
 
+// profile applies and verifies the receiver profile in the background, it exits on device close or when ctx is done
+func profile(ctx context.Context, dev *GpsDevice, p Profile, stop chan struct{}) {
+	dev.watchdogs.Add(1)
+	go func() {
+		defer dev.watchdogs.Done()
+		reopen, err := applyProfile(ctx, dev, p, stop)
+		profileEvent(dev, p, reopen, err, stop)
+	}()
+}
+
+// baudFallback returns to the configured baud rate, if no valid data arrived at the profile rate.
+// The profile is re-applied on reopen, without switching to the failed rate again. A trip while
+// the switch is pending, before the open at the new rate, keeps the switch.
+func baudFallback(dev *GpsDevice) {
+	baud := dev.baud.Load()
+	if baud > 0 && int64(dev.serial.Baud) == baud && dev.baud.CompareAndSwap(baud, 0) {
+		dev.baudFail.Store(baud)
+	}
+}
+
+// trip counts and reports a watchdog trip
+func trip(dev *GpsDevice, kind EventKind) {
+	dev.ErrCount.Add(1)
+	emit(dev, Event{Kind: kind})
+}
+
+// checksumMode ...
+func (dev *GpsDevice) checksumMode() nmeanano.ChecksumMode {
+	if dev.RequireChecksum {
+		return nmeanano.ChecksumRequired
+	}
+	return nmeanano.ChecksumOptional
+}
+
+// checkSumEvent ...
+func checkSumEvent(dev *GpsDevice, sentence string) bool {
+	if nmeanano.ChecksumValid(sentence, dev.checksumMode()) {
+		return true
+	}
+	dev.Record(sentence, OutcomeChecksum, time.Now())
+	emit(dev, Event{Kind: EventChecksum, Detail: sentence})
+	return false
+}
+
+// parseSentence ...
+func parseSentence(dev *GpsDevice, sentence string) (nmeanano.Sentence, error) {
+	return nmeanano.ParseMode(sentence, dev.checksumMode())
+}
+
+// responsiveTimeout ...
+func (dev *GpsDevice) responsiveTimeout() time.Duration {
+	if dev.ResponsiveTimeout > 0 {
+		return dev.ResponsiveTimeout
+	}
+	return _deviceResponsiveTimeout * time.Second
+}
+
+// dataValidTimeout ...
+func (dev *GpsDevice) dataValidTimeout() time.Duration {
+	if dev.DataValidTimeout > 0 {
+		return dev.DataValidTimeout
+	}
+	return _deviceDataValidTimeout * time.Second
+}
+
+// sleep waits for d, returns false if interrupted by stop or ctx
+func sleep(ctx context.Context, stop chan struct{}, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-stop:
+	case <-ctx.Done():
+	}
+	return false
+}
+
 //
 // Little Helper
 //
@@ -107,8 +229,6 @@ const (
 	__OFF   = "\033[0m"
 	__RED   = "\033[2;31m"
 	__GREEN = "\033[2;32m"
-
-	digits = "0123456789ABCDEFX"
 )
 
 // checkSumTag ...
@@ -121,19 +241,5 @@ func checkSumTag(sentence string) string {
 
 // checkSumValid ...
 func checkSumValid(sentence string) bool {
-	t := strings.Split(sentence, _checksep)
-	r := t[0][1:]
-	return checkSum(r) == t[1]
-}
-
-// checkSum ...
-func checkSum(s string) string {
-	var sum uint8
-	for i := 0; i < len(s); i++ {
-		sum ^= s[i]
-	}
-	var r []byte
-	r = append(r, digits[sum>>4])
-	r = append(r, digits[sum&0xF])
-	return string(r)
+	return nmeanano.ChecksumValid(sentence, nmeanano.ChecksumRequired)
 }
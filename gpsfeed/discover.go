@@ -0,0 +1,160 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Discover
+//
+
+// discover probes all candidate devices concurrently for the time window
+func discover(ctx context.Context, window time.Duration) []Discovery {
+	if window <= 0 {
+		window = _autoBaudWindow
+	}
+	var (
+		targets = make(map[string]*Discovery)
+		order   []string
+	)
+	for _, pattern := range DiscoverPatterns {
+		names, _ := filepath.Glob(pattern)
+		for _, name := range names {
+			if !isDevice(name) {
+				continue
+			}
+			target, err := filepath.EvalSymlinks(name)
+			if err != nil {
+				continue
+			}
+			d, ok := targets[target]
+			if !ok {
+				d = &Discovery{Device: target}
+				targets[target] = d
+				order = append(order, target)
+			}
+			if name != target {
+				d.Aliases = append(d.Aliases, name)
+			}
+		}
+	}
+	usb, _ := usbTTYs()
+	var wg sync.WaitGroup
+	for _, target := range order {
+		d := targets[target]
+		for _, t := range usb {
+			if t.path == d.Device {
+				d.USB = USBMatch{Vendor: t.vendor, Product: t.product, Serial: t.serial}
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeDevice(ctx, d, window)
+		}()
+	}
+	wg.Wait()
+	list := make([]Discovery, 0, len(order))
+	for _, target := range order {
+		list = append(list, *targets[target])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Device < list[j].Device })
+	return list
+}
+
+// _pmtkRelease queries the mediatek firmware release, answered by PMTK705
+const _pmtkRelease = "PMTK605"
+
+// probeDevice reads from the device for the time window, with its current port settings. A writable
+// device is queried for its mediatek firmware release, most modules never send PMTK705 unprompted.
+func probeDevice(ctx context.Context, d *Discovery, window time.Duration) {
+	handle, writable, err := openFile(d.Device)
+	if err != nil {
+		d.Err = err
+		return
+	}
+	defer handle.Close()
+	stop := context.AfterFunc(ctx, func() { handle.Close() })
+	defer stop()
+	start := time.Now()
+	if handle.SetReadDeadline(start.Add(window)) != nil {
+		timer := time.AfterFunc(window, func() { handle.Close() }) // no deadline support, unblock via close
+		defer timer.Stop()
+	}
+	src := &fileSource{path: d.Device}
+	src.set(handle)
+	dev := &GpsDevice{FileIO: d.Device, Source: src}
+	feed := newScanner(handle, nil, dev.waiters.dispatch)
+	if writable {
+		go func() { // the reply is picked up by the scan loop
+			request(dev, []byte(formatCommand(_pmtkRelease)), MatchPrefix("$PMTK705"+_sep), window)
+		}()
+	}
+	talkers := make(map[string]bool)
+	for feed.Scan() {
+		line := feed.Text()
+		if kindOf(feed.Bytes()) != FrameNMEA || line[0] == '\\' {
+			continue
+		}
+		d.Total++
+		if !checkSumValid(line) {
+			continue
+		}
+		d.Valid++
+		if talker := talkerOf(line); talker != "" {
+			talkers[talker] = true
+		}
+		if model := modelOf(line); len(model) > len(d.Model) { // most specific wins
+			d.Model = model
+		}
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		d.Rate = float64(d.Valid) / elapsed
+	}
+	for talker := range talkers {
+		d.Talkers = append(d.Talkers, talker)
+	}
+	sort.Strings(d.Talkers)
+}
+
+// talkerOf returns the talker id of a sentence, P for proprietary sentences
+func talkerOf(sentence string) string {
+	if len(sentence) < 3 {
+		return ""
+	}
+	if sentence[1] == 'P' {
+		return "P"
+	}
+	return sentence[1:3]
+}
+
+const _modelUBX = "u-blox"
+
+// modelOf returns the receiver model, if the sentence reveals it [PMTK705, PUBX, TXT]
+func modelOf(sentence string) string {
+	fields := strings.Split(strings.SplitN(sentence, _checksep, 2)[0], _sep)
+	switch {
+	case fields[0] == "$PMTK705" && len(fields) > 3:
+		return "MediaTek " + fields[3] + " [" + fields[1] + "]"
+	case fields[0] == "$PUBX":
+		return _modelUBX
+	case len(fields[0]) == 6 && strings.HasSuffix(fields[0], "TXT") && len(fields) > 4:
+		text := strings.TrimSpace(fields[4])
+		switch {
+		case strings.HasPrefix(text, "MOD="):
+			return _modelUBX + " " + strings.TrimPrefix(text, "MOD=")
+		case strings.HasPrefix(text, "HW "):
+			return _modelUBX + " " + strings.Fields(text)[1]
+		case strings.HasPrefix(strings.ToLower(text), "u-blox"):
+			return _modelUBX
+		}
+	}
+	return ""
+}
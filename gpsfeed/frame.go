@@ -0,0 +1,202 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sync/atomic"
+
+	"paepcke.de/gpsinfo/rtcm3"
+	"paepcke.de/gpsinfo/ubx"
+)
+
+const (
+	_maxLine  = 1024     // max nmea line length [incl. tag block], longer lines are discarded
+	_maxToken = 64 << 10 // scanner buffer limit
+)
+
+//
+// Framing
+//
+
+// kindOf ...
+func kindOf(token []byte) FrameKind {
+	switch {
+	case len(token) == 0:
+		return FrameUnknown
+	case token[0] == '$' || token[0] == '!' || token[0] == '\\':
+		return FrameNMEA
+	case ubx.IsFrame(token):
+		return FrameUBX
+	case token[0] == rtcm3.Preamble:
+		return FrameRTCM3
+	}
+	return FrameUnknown
+}
+
+// splitFrames returns the next nmea line, ubx or rtcm3 frame, leading garbage is skipped within the same call
+// [a bufio.Scanner stops on a skip without token once at EOF]
+func splitFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	skipped, advance, token, err := splitNext(data, atEOF)
+	return skipped + advance, token, err
+}
+
+// splitNext skips garbage until the next token, or until more data is needed
+func splitNext(data []byte, atEOF bool) (skipped, advance int, token []byte, err error) {
+	for skipped < len(data) {
+		advance, token, err = splitFrame(data[skipped:], atEOF)
+		if err != nil || token != nil || advance == 0 {
+			return skipped, advance, token, err
+		}
+		skipped += advance
+	}
+	return skipped, 0, nil, nil
+}
+
+// splitFrame returns the next nmea line, ubx or rtcm3 frame, or skips garbage [advance > 0, token == nil]
+func splitFrame(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+	switch data[0] {
+	case '$', '!', '\\':
+		return splitLine(data, atEOF)
+	case ubx.Sync1, rtcm3.Preamble:
+		match := rtcm3.Match
+		if data[0] == ubx.Sync1 {
+			match = ubx.Match
+		}
+		n, ok := match(data, atEOF)
+		switch {
+		case !ok:
+			return 1, nil, nil // false sync
+		case n == 0:
+			return 0, nil, nil // need more data
+		}
+		return n, data[:n], nil
+	}
+	if i := nextStart(data[1:]); i > -1 {
+		return i + 1, nil, nil
+	}
+	return len(data), nil, nil
+}
+
+// nextStart returns the index of the next possible frame start, -1 if none
+func nextStart(data []byte) int {
+	for i, c := range data {
+		switch c {
+		case '$', '!', '\\', ubx.Sync1, rtcm3.Preamble:
+			return i
+		}
+	}
+	return -1
+}
+
+// splitLine ...
+func splitLine(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	tagged := data[0] == '\\'
+	for i := 1; i < len(data) && i < _maxLine; i++ {
+		switch c := data[i]; {
+		case c == '\n':
+			return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+		case c == '\r':
+		case c == '$' || c == '!':
+			if !tagged || data[i-1] != '\\' {
+				return i, nil, nil // sentence restart, drop the truncated one
+			}
+		case c < 0x20 || c > 0x7E:
+			return i, nil, nil // binary data interrupts text
+		}
+	}
+	if len(data) >= _maxLine {
+		return _maxLine, nil, nil
+	}
+	if atEOF {
+		return len(data), bytes.TrimSuffix(data, []byte{'\r'}), nil
+	}
+	return 0, nil, nil
+}
+
+// newScanner returns a framing scanner, garbage gets the skipped byte counts, tap sees every token
+func newScanner(r io.Reader, garbage func(n int), tap func(token []byte)) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 4096), _maxToken)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		skipped, advance, token, err := splitNext(data, atEOF)
+		if skipped > 0 && garbage != nil {
+			garbage(skipped)
+		}
+		if token != nil && tap != nil {
+			tap(token)
+		}
+		return skipped + advance, token, err
+	})
+	return s
+}
+
+//
+// Demux
+//
+
+// newDemux ...
+func newDemux(size int) *Demux {
+	return &Demux{
+		NMEA:  make(chan string, size),
+		UBX:   make(chan []byte, size),
+		RTCM3: make(chan []byte, size),
+	}
+}
+
+// run ...
+func (d *Demux) run(feed *bufio.Scanner, dev *GpsDevice) error {
+	defer func() {
+		if d.NMEA != nil {
+			close(d.NMEA)
+		}
+		closeFrames(d.UBX)
+		closeFrames(d.RTCM3)
+	}()
+	for feed.Scan() {
+		token := feed.Bytes()
+		if dev != nil {
+			dev.Responsive.Store(true)
+		}
+		switch kindOf(token) {
+		case FrameNMEA:
+			if d.NMEA == nil {
+				continue
+			}
+			select {
+			case d.NMEA <- string(token):
+			default:
+				d.DroppedNMEA.Add(1)
+			}
+		case FrameUBX:
+			sendFrame(d.UBX, token, &d.DroppedUBX)
+		case FrameRTCM3:
+			sendFrame(d.RTCM3, token, &d.DroppedRTCM3)
+		}
+	}
+	return feed.Err()
+}
+
+// sendFrame passes a copy of the frame without blocking, a full channel counts a drop, a nil one skips
+func sendFrame(ch chan []byte, token []byte, dropped *atomic.Uint64) {
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- append([]byte(nil), token...):
+	default:
+		dropped.Add(1)
+	}
+}
+
+// closeFrames ...
+func closeFrames(ch chan []byte) {
+	if ch != nil {
+		close(ch)
+	}
+}
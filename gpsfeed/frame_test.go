@@ -0,0 +1,130 @@
+package gpsfeed
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+	"paepcke.de/gpsinfo/ubx"
+)
+
+// line returns a checksummed nmea sentence without line end
+func line(s string) string { return "$" + s + "*" + nmeanano.Checksum(s) }
+
+var (
+	_rmc = line("GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W")
+	_gga = line("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")
+	_ubx = string(ubx.Encode(ubx.Frame{Class: ubx.ClassNAV, ID: ubx.IDNavTime, Payload: []byte{1, 2, 3, 4}}))
+)
+
+// rtcmFrame returns a crc-24q protected rtcm3 frame
+func rtcmFrame(payload []byte) string {
+	frame := append([]byte{0xD3, byte(len(payload) >> 8), byte(len(payload))}, payload...)
+	var crc uint32
+	for _, x := range frame {
+		crc ^= uint32(x) << 16
+		for i := 0; i < 8; i++ {
+			if crc <<= 1; crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+	}
+	return string(append(frame, byte(crc>>16), byte(crc>>8), byte(crc)))
+}
+
+// scan returns all tokens and the skipped byte count of the input, read via the reader wrapper
+func scan(in string, wrap func(io.Reader) io.Reader) (tokens []string, garbage int) {
+	s := newScanner(wrap(strings.NewReader(in)), func(n int) { garbage += n }, nil)
+	for s.Scan() {
+		tokens = append(tokens, s.Text())
+	}
+	return tokens, garbage
+}
+
+// TestSplitFrames ...
+func TestSplitFrames(t *testing.T) {
+	rtcm := rtcmFrame([]byte{0x3E, 0xD0, 0x00, 0x03})
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{name: "ubx false sync before line", in: "\xb5\x62garbage" + _rmc + "\n", want: []string{_rmc}},
+		{name: "rtcm false sync between lines", in: _rmc + "\n\xd3\x00\x13junk\n" + _gga + "\n", want: []string{_rmc, _gga}},
+		{name: "truncated ubx at eof", in: _rmc + "\n\xb5\x62\x01\x07\xff\x00" + _gga, want: []string{_rmc, _gga}},
+		{name: "truncated ubx header at eof", in: _rmc + "\n\xb5\x62\x01", want: []string{_rmc}},
+		{name: "mixed frames and garbage", in: "xx" + _ubx + "\x00\x01" + _rmc + "\r\n\xff" + rtcm + "junk" + _gga, want: []string{_ubx, _rmc, rtcm, _gga}},
+		{name: "truncated line restart", in: "$GPRMC,1235" + _gga + "\n" + "\xd3", want: []string{_gga}},
+		{name: "garbage only", in: "\x00\x01\x02\xb5\xd3", want: nil},
+		{name: "empty", in: "", want: nil},
+	}
+	readers := map[string]func(io.Reader) io.Reader{
+		"plain":    func(r io.Reader) io.Reader { return r },
+		"data+eof": iotest.DataErrReader,
+		"one byte": iotest.OneByteReader,
+	}
+	for _, tt := range tests {
+		for name, wrap := range readers {
+			got, garbage := scan(tt.in, wrap)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s [%s]: tokens %q, want %q", tt.name, name, got, tt.want)
+			}
+			if tokens := len(strings.Join(got, "")); garbage > len(tt.in)-tokens {
+				t.Errorf("%s [%s]: garbage %d, input %d, tokens %d", tt.name, name, garbage, len(tt.in), tokens)
+			}
+		}
+	}
+}
+
+// TestDemuxRunReader ...
+func TestDemuxRunReader(t *testing.T) {
+	rtcm := rtcmFrame([]byte{0x3E, 0xD0, 0x00, 0x03})
+	d := newDemux(10)
+	if err := d.RunReader(iotest.DataErrReader(strings.NewReader("\xb5\x62junk" + _rmc + "\n" + _ubx + "\xd3\x00\x13junk\n" + rtcm + _gga))); err != nil {
+		t.Fatal(err)
+	}
+	var nmea []string
+	for s := range d.NMEA {
+		nmea = append(nmea, s)
+	}
+	if want := []string{_rmc, _gga}; !slices.Equal(nmea, want) {
+		t.Errorf("nmea %q, want %q", nmea, want)
+	}
+	if f := <-d.UBX; !bytes.Equal(f, []byte(_ubx)) {
+		t.Errorf("ubx %X", f)
+	}
+	if f := <-d.RTCM3; !bytes.Equal(f, []byte(rtcm)) {
+		t.Errorf("rtcm3 %X", f)
+	}
+}
+
+// TestDemuxDrops runs a demux without consumer, full channels must drop frames instead of blocking the feed
+func TestDemuxDrops(t *testing.T) {
+	d := newDemux(2)
+	d.RTCM3 = nil // unused stream
+	in := strings.Repeat(_rmc+"\n"+_ubx+_ubx+_ubx+_ubx+rtcmFrame([]byte{0x3E, 0xD0, 0x00, 0x03}), 5)
+	done := make(chan error, 1)
+	go func() { done <- d.RunReader(strings.NewReader(in)) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("demux blocked on a full channel")
+	}
+	if n := len(d.NMEA); n != 2 || d.DroppedNMEA.Load() != 3 {
+		t.Errorf("nmea delivered %d, dropped %d, want 2, 3", n, d.DroppedNMEA.Load())
+	}
+	if n := len(d.UBX); n != 2 || d.DroppedUBX.Load() != 18 {
+		t.Errorf("ubx delivered %d, dropped %d, want 2, 18", n, d.DroppedUBX.Load())
+	}
+	if d.DroppedRTCM3.Load() != 0 {
+		t.Errorf("skipped rtcm3 counted as dropped [%d]", d.DroppedRTCM3.Load())
+	}
+}
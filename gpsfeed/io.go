@@ -1,6 +1,10 @@
 package gpsfeed
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
@@ -23,26 +27,57 @@ func outPlain(msg string) { os.Stdout.Write([]byte(msg)) }
 //
 
 const (
+	_stdin              = "-"
 	_modeDevice  uint32 = 1 << (32 - 1 - 5)
 	_modeSymlink uint32 = 1 << (32 - 1 - 4)
 )
 
-// getHandle
-func getHandle(dev *GpsDevice) (handle *os.File, ok bool) {
-	var err error
-	handle, err = os.Open(dev.FileIO)
-	if err != nil {
-		if dev.CheckErrAdd() {
-			out("[error]" + "[" + dev.FileIO + "] [" + err.Error() + "] [" + dev.GetErr() + "]")
-		}
-		time.Sleep(DeviceTimeout * time.Second)
-		return handle, false
+// getHandle opens the device source, waits for the reconnect delay on failure
+func getHandle(ctx context.Context, dev *GpsDevice) (ok bool, err error) {
+	openErr := dev.Source.Open()
+	if openErr == nil {
+		dev.attempts.Store(0)
+		dev.nextRetry.Store(0)
+		emit(dev, Event{Kind: EventOpened, Detail: sourcePath(dev.Source), Baud: dev.serial.Baud})
+		return true, nil
+	}
+	dev.ErrCount.Add(1)
+	now := time.Now()
+	attempt := int(dev.attempts.Add(1))
+	if attempt == 1 {
+		dev.failed.Store(now.UnixNano())
 	}
-	return handle, true
+	delay, giveUp := retryDelay(dev, attempt, now.Sub(time.Unix(0, dev.failed.Load())))
+	if giveUp || errors.Is(openErr, ErrSourceEnded) {
+		dev.nextRetry.Store(0)
+		emit(dev, Event{Kind: EventGiveUp, Err: openErr, Attempt: attempt})
+		return false, fmt.Errorf("%w [%s] [%w]", ErrGiveUp, dev.FileIO, openErr)
+	}
+	retry := now.Add(delay)
+	dev.nextRetry.Store(retry.UnixNano())
+	emit(dev, Event{Kind: EventReconnect, Err: openErr, Attempt: attempt, Retry: retry})
+	sleep(ctx, nil, delay)
+	return false, nil
 }
 
 // getDevice
 func getDevice(device, old string) string {
+	if device == _stdin {
+		out("[info] [stdin]")
+		return device
+	}
+	if isNetwork(device) {
+		out("[info] [network] [" + device + "]")
+		return device
+	}
+	if isUSB(device) {
+		if _, err := parseUSB(device); err != nil {
+			out("[error] [skip] [" + err.Error() + "]")
+			return old
+		}
+		out("[info] [usb] [" + device + "]")
+		return device
+	}
 	if isDevice(device) {
 		out("[info] [device] [" + device + "]")
 		return device
@@ -57,11 +92,159 @@ func isDevice(devicename string) bool {
 	if err != nil {
 		return false
 	}
-	if uint32(fi.Mode())&_modeDevice != 0 || uint32(fi.Mode())&_modeSymlink != 0 {
-		// TODO validate symlink target for device mode
-		return true
+	if uint32(fi.Mode())&_modeSymlink != 0 {
+		if fi, err = os.Stat(devicename); err != nil { // dangling symlink, eg. unplugged /dev/serial/by-id/...
+			return false
+		}
+	}
+	return uint32(fi.Mode())&_modeDevice != 0
+}
+
+// sourcePath returns the resolved device path of a source, if it differs from its name
+func sourcePath(s Source) string {
+	if p, ok := s.(interface{ Path() string }); ok && p.Path() != s.Name() {
+		return p.Path()
+	}
+	return ""
+}
+
+//
+// EVENTS
+//
+
+// eventName ...
+func eventName(k EventKind) string {
+	switch k {
+	case EventUnresponsive:
+		return "unresponsive"
+	case EventInvalidData:
+		return "invalid data"
+	case EventOpened:
+		return "opened"
+	case EventClosed:
+		return "closed"
+	case EventChecksum:
+		return "checksum"
+	case EventReconnect:
+		return "reconnect"
+	case EventGiveUp:
+		return "give up"
+	case EventProfile:
+		return "profile"
+	case EventProfileError:
+		return "profile error"
+	case EventBaudProbe:
+		return "baud probe"
+	case EventBaudLocked:
+		return "baud locked"
+	case EventBaudFailed:
+		return "baud failed"
+	}
+	return "unknown"
+}
+
+// eventLevel ...
+func eventLevel(k EventKind) slog.Level {
+	switch k {
+	case EventUnresponsive, EventInvalidData, EventGiveUp, EventProfileError, EventBaudFailed:
+		return slog.LevelError
+	case EventChecksum, EventReconnect:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// eventString ...
+func eventString(e Event) string {
+	switch e.Kind {
+	case EventUnresponsive:
+		return _errDeviceUnresponsive + " [" + e.Device + "] [" + strconv.FormatUint(e.ErrCount, 10) + "]"
+	case EventInvalidData:
+		return _errDeviceInvalidData + " [" + e.Device + "] [" + strconv.FormatUint(e.ErrCount, 10) + "]"
+	case EventReconnect:
+		return "[error]" + "[" + e.Device + "] [" + errString(e.Err) + "] [" + strconv.FormatUint(e.ErrCount, 10) + "]"
+	case EventGiveUp:
+		return _err + "[give up] [" + e.Device + "] [" + errString(e.Err) + "] [attempt " + strconv.Itoa(e.Attempt) + "]"
+	case EventProfileError:
+		return _err + "[profile] [" + e.Device + "] [" + errString(e.Err) + "]"
+	case EventBaudProbe:
+		return "[info] [autobaud] [" + e.Device + "] [" + strconv.Itoa(e.Baud) + "] " + e.Detail
+	case EventBaudLocked:
+		return "[info] [autobaud] [" + e.Device + "] [locked] [" + strconv.Itoa(e.Baud) + "]"
+	case EventBaudFailed:
+		return _err + "[autobaud] [no valid nmea data at any baud rate] [" + e.Device + "]"
+	}
+	msg := "[gpsfeed] [" + e.Kind.String() + "] [" + e.Device + "]"
+	if e.Detail != "" {
+		msg += " [" + e.Detail + "]"
+	}
+	return msg
+}
+
+// errString ...
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// emit completes the event and hands it to the device handler, the event channel or the default handler
+func emit(dev *GpsDevice, e Event) {
+	e.Device = dev.FileIO
+	e.Time = time.Now()
+	e.ErrCount = dev.ErrCount.Load()
+	switch {
+	case dev.Handler != nil:
+		dev.Handler.Handle(e)
+	case dev.Events != nil:
+		select {
+		case dev.Events <- e:
+		default: // never block the feed or watchdog, drop if nobody listens
+		}
+	case DefaultHandler != nil:
+		DefaultHandler.Handle(e)
+	}
+}
+
+// stdoutEvent prints watchdog trips, open errors, profile and autobaud results, until the device error limit is reached
+func stdoutEvent(e Event) {
+	switch e.Kind {
+	case EventUnresponsive, EventInvalidData, EventReconnect:
+		if e.ErrCount < _errMax {
+			out(e.String())
+		}
+	case EventGiveUp, EventProfile, EventProfileError, EventBaudProbe, EventBaudLocked, EventBaudFailed:
+		out(e.String())
+	}
+}
+
+// slogHandler ...
+type slogHandler struct{ l *slog.Logger }
+
+// Handle ...
+func (h slogHandler) Handle(e Event) {
+	l := h.l
+	if l == nil {
+		l = slog.Default()
+	}
+	attrs := []slog.Attr{slog.String("device", e.Device), slog.Uint64("errors", e.ErrCount)}
+	if e.Err != nil {
+		attrs = append(attrs, slog.String("err", e.Err.Error()))
+	}
+	if e.Detail != "" {
+		attrs = append(attrs, slog.String("detail", e.Detail))
+	}
+	if e.Attempt > 0 {
+		attrs = append(attrs, slog.Int("attempt", e.Attempt))
+	}
+	if !e.Retry.IsZero() {
+		attrs = append(attrs, slog.Time("retry", e.Retry))
+	}
+	if e.Baud > 0 {
+		attrs = append(attrs, slog.Int("baud", e.Baud))
 	}
-	return false
+	l.LogAttrs(context.Background(), e.Kind.Level(), "gpsfeed: "+e.Kind.String(), attrs...)
 }
 
 //
@@ -0,0 +1,125 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Manager
+//
+
+// receiver holds the per device counters of a Manager
+type receiver struct {
+	dev       *GpsDevice
+	index     int
+	mu        sync.Mutex
+	count     uint64
+	first     time.Time
+	last      time.Time
+	lastValid time.Time
+}
+
+// newManager ...
+func newManager(size int, devs []*GpsDevice) *Manager {
+	m := &Manager{Frames: make(chan Frame, size)}
+	for i, dev := range devs {
+		if dev.FileIO == "" && dev.Source != nil {
+			dev.FileIO = dev.Source.Name() // frames are tagged by device name
+		}
+		m.receivers = append(m.receivers, &receiver{dev: dev, index: i})
+	}
+	return m
+}
+
+// run ...
+func (m *Manager) run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, r := range m.receivers {
+		wg.Add(1)
+		go func(r *receiver) {
+			defer wg.Done()
+			r.feed(ctx, m.Frames)
+		}(r)
+	}
+	wg.Wait()
+	close(m.Frames)
+	return ctx.Err()
+}
+
+// feed opens the device, tags and forwards all frames, reopens after feed loss until ctx is done
+func (r *receiver) feed(ctx context.Context, frames chan Frame) {
+	dev := r.dev
+	for {
+		if err := dev.OpenContext(ctx); err != nil {
+			return
+		}
+		for dev.Feed.Scan() {
+			now := time.Now()
+			dev.Responsive.Store(true)
+			token := dev.Feed.Bytes()
+			kind := kindOf(token)
+			valid := kind == FrameUBX || kind == FrameRTCM3
+			if kind == FrameNMEA && strings.Contains(dev.Feed.Text(), _checksep) {
+				valid = dev.CheckSum(dev.Feed.Text())
+			}
+			if kind == FrameNMEA && valid {
+				dev.Record(dev.Feed.Text(), OutcomeOK, now)
+			}
+			if valid {
+				dev.DataValid.Store(true)
+			}
+			r.mu.Lock()
+			if r.count == 0 {
+				r.first = now
+			}
+			r.count++
+			r.last = now
+			if valid {
+				r.lastValid = now
+			}
+			r.mu.Unlock()
+			select {
+			case frames <- Frame{Device: dev.FileIO, Index: r.index, Kind: kind, Data: append([]byte(nil), token...), Time: now}:
+			case <-ctx.Done():
+			}
+		}
+		dev.Close()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// health ...
+func (r *receiver) health(now time.Time) Health {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := Health{
+		Device:     r.dev.FileIO,
+		Index:      r.index,
+		Responsive: !r.last.IsZero() && now.Sub(r.last) < r.dev.responsiveTimeout(),
+		DataValid:  !r.lastValid.IsZero() && now.Sub(r.lastValid) < r.dev.dataValidTimeout(),
+		ErrCount:   r.dev.ErrCount.Load(),
+		Sentences:  r.count,
+		Last:       r.last,
+	}
+	if d := r.last.Sub(r.first).Seconds(); d > 0 {
+		h.Rate = float64(r.count-1) / d
+	}
+	return h
+}
+
+// health ...
+func (m *Manager) health() []Health {
+	now := time.Now()
+	list := make([]Health, 0, len(m.receivers))
+	for _, r := range m.receivers {
+		list = append(list, r.health(now))
+	}
+	return list
+}
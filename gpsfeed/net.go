@@ -0,0 +1,68 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+//
+// NETWORK IO
+//
+
+// network source url schemes
+const (
+	SchemeTCP       = "tcp://"        // tcp client, eg. tcp://192.168.4.1:10110 [ser2net, wifi gateways]
+	SchemeTCPListen = "tcp-listen://" // tcp server, accepts one sender, eg. tcp-listen://:10110
+	SchemeUDP       = "udp://"        // udp listener, eg. udp://:10110 [broadcast]
+)
+
+// isNetwork ...
+func isNetwork(name string) bool {
+	_, _, err := parseNetwork(name)
+	return err == nil
+}
+
+// parseNetwork splits a network source url into scheme and address
+func parseNetwork(name string) (scheme, addr string, err error) {
+	for _, scheme = range []string{SchemeTCP, SchemeTCPListen, SchemeUDP} {
+		if strings.HasPrefix(name, scheme) {
+			addr = name[len(scheme):]
+			if _, _, err = net.SplitHostPort(addr); err != nil {
+				return "", "", fmt.Errorf("invalid network address [%s] [%w]", name, err)
+			}
+			return scheme, addr, nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown network source [%s]", name)
+}
+
+// openNetwork connects, accepts or listens, bounded by the device timeout
+func openNetwork(name string) (io.ReadCloser, error) {
+	scheme, addr, err := parseNetwork(name)
+	if err != nil {
+		return nil, err
+	}
+	timeout := DeviceTimeout * time.Second
+	switch scheme {
+	case SchemeTCP:
+		return net.DialTimeout("tcp", addr, timeout)
+	case SchemeTCPListen:
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		defer l.Close()
+		l.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
+		return l.Accept()
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
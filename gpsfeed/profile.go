@@ -0,0 +1,469 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"paepcke.de/gpsinfo/ubx"
+)
+
+//
+// Profile
+//
+
+const (
+	_profileTimeout   = 2 * time.Second // ack timeout per command
+	_profileSettle    = time.Second     // sentences of the old configuration still in flight
+	_profileWindow    = 3 * time.Second // verification window [min]
+	_profileTolerance = 0.25            // accepted epoch rate deviation
+	_profileDefault   = "*"
+	_profileMinToken  = 7 // shortest observed sentence, $ttsss,
+)
+
+// pmtk314 field index per sentence type
+var _pmtkSentences = map[string]int{"GLL": 0, "RMC": 1, "VTG": 2, "GGA": 3, "GSA": 4, "GSV": 5, "GRS": 6, "GST": 7, "ZDA": 17}
+
+// ubx nmea message id [class F0] per sentence type
+var _ubxSentences = map[string]byte{"GGA": 0x00, "GLL": 0x01, "GSA": 0x02, "GSV": 0x03, "RMC": 0x04, "VTG": 0x05, "GRS": 0x06, "GST": 0x07, "ZDA": 0x08, "GBS": 0x09, "DTM": 0x0A, "GNS": 0x0D}
+
+// gnss constellations: ubx gnssId, reserved and max tracking channels, gsv talkers
+var _gnss = map[string]struct {
+	id, res, max byte
+	talkers      []string
+}{
+	"gps":     {0, 8, 16, []string{"GP"}},
+	"galileo": {2, 4, 8, []string{"GA"}},
+	"beidou":  {3, 8, 16, []string{"GB", "BD"}},
+	"qzss":    {5, 0, 3, []string{"GQ", "QZ"}},
+	"glonass": {6, 8, 14, []string{"GL"}},
+}
+
+// parseProfile ...
+func parseProfile(s string) (Profile, error) {
+	var p Profile
+	for _, opt := range strings.Fields(s) {
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok || value == "" {
+			return Profile{}, fmt.Errorf("invalid profile option [%s]", opt)
+		}
+		switch key {
+		case "protocol":
+			p.Protocol = strings.ToLower(value)
+		case "rate":
+			r, err := strconv.ParseFloat(value, 64)
+			if err != nil || r <= 0 || r > 50 {
+				return Profile{}, fmt.Errorf("invalid profile rate [%s]", value)
+			}
+			p.Rate = r
+		case "sentences":
+			p.Sentences = strings.Split(strings.ToUpper(value), _sep)
+		case "gnss":
+			p.GNSS = strings.Split(strings.ToLower(value), _sep)
+		case "sbas":
+			on, err := parseSwitch(value)
+			if err != nil {
+				return Profile{}, err
+			}
+			p.SBAS = &on
+		case "baud":
+			b, err := strconv.Atoi(value)
+			if err != nil || !slices.Contains(BaudRates, b) {
+				return Profile{}, fmt.Errorf("invalid profile baud rate [%s]", value)
+			}
+			p.Baud = b
+		default:
+			return Profile{}, fmt.Errorf("invalid profile option [%s]", opt)
+		}
+	}
+	return p, p.validate()
+}
+
+// parseSwitch ...
+func parseSwitch(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "true", "1":
+		return true, nil
+	case "off", "false", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid profile switch [%s]", s)
+}
+
+// validate ...
+func (p Profile) validate() error {
+	var known func(string) bool
+	switch p.Protocol {
+	case ProtocolPMTK:
+		known = func(s string) bool { _, ok := _pmtkSentences[s]; return ok }
+	case ProtocolUBX:
+		known = func(s string) bool { _, ok := _ubxSentences[s]; return ok }
+	default:
+		return fmt.Errorf("invalid profile protocol [%s]", p.Protocol)
+	}
+	for _, s := range p.Sentences {
+		if !known(s) {
+			return fmt.Errorf("unsupported profile sentence [%s] [%s]", p.Protocol, s)
+		}
+	}
+	for _, g := range p.GNSS {
+		if _, ok := _gnss[g]; !ok || p.Protocol == ProtocolPMTK && g == "qzss" {
+			return fmt.Errorf("unsupported profile constellation [%s] [%s]", p.Protocol, g)
+		}
+	}
+	return nil
+}
+
+// string ...
+func (p Profile) string() string {
+	s := "protocol=" + p.Protocol
+	if p.Rate > 0 {
+		s += " rate=" + strconv.FormatFloat(p.Rate, 'f', -1, 64)
+	}
+	if p.Sentences != nil {
+		s += " sentences=" + strings.Join(p.Sentences, _sep)
+	}
+	if p.GNSS != nil {
+		s += " gnss=" + strings.Join(p.GNSS, _sep)
+	}
+	if p.SBAS != nil {
+		s += " sbas=" + map[bool]string{true: "on", false: "off"}[*p.SBAS]
+	}
+	if p.Baud > 0 {
+		s += " baud=" + strconv.Itoa(p.Baud)
+	}
+	return s
+}
+
+// readProfiles parses a profile file, one device per line: <device> <key=value> ..., # starts a comment
+func readProfiles(r io.Reader) (Profiles, error) {
+	profiles := make(Profiles)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		p, err := parseProfile(strings.Join(fields[1:], " "))
+		if err != nil {
+			return nil, fmt.Errorf("profile line %d [%s] [%w]", n, fields[0], err)
+		}
+		profiles[fields[0]] = p
+	}
+	return profiles, s.Err()
+}
+
+// loadProfiles ...
+func loadProfiles(path string) (Profiles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readProfiles(f)
+}
+
+// lookup returns the device profile, or the default profile
+func (ps Profiles) lookup(device string) *Profile {
+	for _, name := range []string{device, _profileDefault} {
+		if p, ok := ps[name]; ok {
+			return &p
+		}
+	}
+	return nil
+}
+
+//
+// Apply
+//
+
+// profileEvent reports the outcome of an apply, unless the device got closed meanwhile
+func profileEvent(dev *GpsDevice, p Profile, reopen bool, err error, stop chan struct{}) {
+	select {
+	case <-stop:
+		if !reopen {
+			return
+		}
+	default:
+	}
+	switch {
+	case err != nil:
+		emit(dev, Event{Kind: EventProfileError, Err: err, Detail: p.string()})
+	case reopen:
+		emit(dev, Event{Kind: EventProfile, Detail: "baud=" + strconv.Itoa(p.Baud) + " [reopen]"})
+	default:
+		emit(dev, Event{Kind: EventProfile, Detail: p.string()})
+	}
+}
+
+// applyProfile configures the receiver, switches the baud rate last and verifies the sentence mix that follows,
+// a baud switch closes the source, the consumer reopens at the new rate
+func applyProfile(ctx context.Context, dev *GpsDevice, p Profile, stop chan struct{}) (reopen bool, err error) {
+	if err := p.validate(); err != nil {
+		return false, err
+	}
+	var cmds []func() error
+	switch p.Protocol {
+	case ProtocolPMTK:
+		cmds = pmtkProfile(dev, p)
+	case ProtocolUBX:
+		cmds = ubxProfile(dev, p)
+	}
+	for _, cmd := range cmds {
+		select {
+		case <-stop:
+			return false, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		default:
+		}
+		if err := cmd(); err != nil {
+			return false, err
+		}
+	}
+	if p.Baud > 0 && isSerial(dev.Source) && dev.Serial != nil && dev.serial.Baud != p.Baud {
+		if dev.baudFail.Load() == int64(p.Baud) {
+			return false, fmt.Errorf("%w [%s] [no valid data at %d baud, kept %d]", ErrProfile, dev.FileIO, p.Baud, dev.serial.Baud)
+		}
+		return true, switchBaud(dev, p)
+	}
+	return false, verifyProfile(ctx, dev, p, stop)
+}
+
+// pmtkProfile returns the mediatek commands for the profile
+func pmtkProfile(dev *GpsDevice, p Profile) (cmds []func() error) {
+	add := func(cmd string) { cmds = append(cmds, func() error { return sendPMTK(dev, cmd, _profileTimeout) }) }
+	if p.Rate > 0 {
+		add("PMTK220," + strconv.Itoa(int(math.Round(1000/p.Rate))))
+	}
+	if p.Sentences != nil {
+		fields := make([]string, 19)
+		for i := range fields {
+			fields[i] = "0"
+		}
+		for _, s := range p.Sentences {
+			fields[_pmtkSentences[s]] = "1"
+		}
+		add("PMTK314," + strings.Join(fields, _sep))
+	}
+	if p.GNSS != nil {
+		on := func(g string) string { return map[bool]string{true: "1", false: "0"}[slices.Contains(p.GNSS, g)] }
+		add("PMTK353," + on("gps") + _sep + on("glonass") + _sep + on("galileo") + _sep + on("galileo") + _sep + on("beidou"))
+	}
+	if p.SBAS != nil {
+		if *p.SBAS {
+			add("PMTK313,1")
+			add("PMTK301,2")
+		} else {
+			add("PMTK313,0")
+			add("PMTK301,0")
+		}
+	}
+	return cmds
+}
+
+// ubxProfile returns the u-blox cfg messages for the profile
+func ubxProfile(dev *GpsDevice, p Profile) (cmds []func() error) {
+	add := func(id byte, payload []byte) {
+		cmds = append(cmds, func() error { return sendUBX(dev, ubx.ClassCFG, id, payload, _profileTimeout) })
+	}
+	if p.Rate > 0 {
+		payload := binary.LittleEndian.AppendUint16(nil, uint16(math.Round(1000/p.Rate))) // measRate [ms]
+		payload = binary.LittleEndian.AppendUint16(payload, 1)                            // navRate [cycles]
+		payload = binary.LittleEndian.AppendUint16(payload, 1)                            // timeRef [gps]
+		add(_ubxCfgRate, payload)
+	}
+	if p.Sentences != nil {
+		for _, s := range slices.Sorted(maps.Keys(_ubxSentences)) {
+			rate := byte(0)
+			if slices.Contains(p.Sentences, s) {
+				rate = 1
+			}
+			add(_ubxCfgMsg, []byte{_ubxClassNMEA, _ubxSentences[s], rate})
+		}
+	}
+	if p.GNSS != nil {
+		payload := []byte{0, 0, 0xFF, byte(len(_gnss))} // msgVer, numTrkChHw, numTrkChUse, numConfigBlocks
+		for _, g := range slices.Sorted(maps.Keys(_gnss)) {
+			c := _gnss[g]
+			flags := uint32(0x010000) // sigCfgMask: L1C/A, E1, B1I, L1OF
+			if slices.Contains(p.GNSS, g) {
+				flags |= 1
+			}
+			payload = append(payload, c.id, c.res, c.max, 0)
+			payload = binary.LittleEndian.AppendUint32(payload, flags)
+		}
+		add(_ubxCfgGNSS, payload)
+	}
+	if p.SBAS != nil {
+		mode := byte(0)
+		if *p.SBAS {
+			mode = 1
+		}
+		add(_ubxCfgSBAS, []byte{mode, 0x03, 3, 0, 0, 0, 0, 0}) // mode, usage [range, diffcorr], maxSBAS, scanmode
+	}
+	return cmds
+}
+
+// ubx cfg message ids
+const (
+	_ubxCfgPort   = 0x00
+	_ubxCfgMsg    = 0x01
+	_ubxCfgRate   = 0x08
+	_ubxCfgSBAS   = 0x16
+	_ubxCfgGNSS   = 0x3E
+	_ubxClassNMEA = 0xF0
+)
+
+// isSerial reports if the source is a device file, a baud rate applies
+func isSerial(s Source) bool {
+	switch s.(type) {
+	case *fileSource, *usbSource:
+		return true
+	}
+	return false
+}
+
+// switchBaud sends the baud rate command without ack [the receiver answers at the new rate],
+// switches the serial settings of the next open to the new rate and closes the source. Serial keeps
+// the configured rate, the fallback once no valid data arrives at the new rate.
+func switchBaud(dev *GpsDevice, p Profile) error {
+	var err error
+	switch p.Protocol {
+	case ProtocolPMTK:
+		err = write(dev, []byte(formatCommand("PMTK251,"+strconv.Itoa(p.Baud))))
+	case ProtocolUBX:
+		payload := []byte{1, 0, 0, 0}                               // portID [uart1], reserved, txReady
+		payload = binary.LittleEndian.AppendUint32(payload, 0x08D0) // mode [8N1]
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(p.Baud))
+		payload = binary.LittleEndian.AppendUint16(payload, 0x0007) // inProtoMask [ubx, nmea, rtcm]
+		payload = binary.LittleEndian.AppendUint16(payload, 0x0003) // outProtoMask [ubx, nmea]
+		payload = append(payload, 0, 0, 0, 0)                       // flags, reserved
+		err = write(dev, ubx.Encode(ubx.Frame{Class: ubx.ClassCFG, ID: _ubxCfgPort, Payload: payload}))
+	}
+	if err != nil {
+		return err
+	}
+	time.Sleep(100 * time.Millisecond) // drain the uart before the switch
+	dev.baud.Store(int64(p.Baud))
+	dev.Source.Close()
+	return nil
+}
+
+//
+// Verify
+//
+
+// observer records the sentence mix of a device feed
+type observer struct {
+	mu      sync.Mutex
+	types   map[string]int
+	talkers map[string]bool // gsv talkers
+	times   map[string][]time.Time
+}
+
+// observe counts every nmea sentence, it never matches
+func (o *observer) observe(token []byte) bool {
+	if kindOf(token) != FrameNMEA || len(token) < _profileMinToken {
+		return false
+	}
+	talker, typ := addressOf(string(token))
+	if typ == "" || talker == "P" {
+		return false
+	}
+	now := time.Now()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.types[typ]++
+	o.times[typ] = append(o.times[typ], now)
+	if typ == "GSV" {
+		o.talkers[talker] = true
+	}
+	return false
+}
+
+// verifyProfile waits for the new configuration to settle and checks the sentence mix that follows
+func verifyProfile(ctx context.Context, dev *GpsDevice, p Profile, stop chan struct{}) error {
+	if !sleep(ctx, stop, _profileSettle) {
+		return ctx.Err()
+	}
+	window := _profileWindow
+	if p.Rate > 0 && time.Duration(3/p.Rate*float64(time.Second)) > window {
+		window = time.Duration(3 / p.Rate * float64(time.Second))
+	}
+	o := &observer{types: make(map[string]int), talkers: make(map[string]bool), times: make(map[string][]time.Time)}
+	w := dev.waiters.add(o.observe)
+	ok := sleep(ctx, stop, window)
+	dev.waiters.remove(w)
+	if !ok {
+		return ctx.Err()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.types) == 0 {
+		return fmt.Errorf("%w [%s] [no nmea sentences]", ErrProfile, dev.FileIO)
+	}
+	var problems []string
+	for _, s := range p.Sentences {
+		if o.types[s] == 0 {
+			problems = append(problems, "missing "+s)
+		}
+	}
+	if p.Sentences != nil {
+		for s := range o.types {
+			if !slices.Contains(p.Sentences, s) && p.controls(s) {
+				problems = append(problems, "unexpected "+s)
+			}
+		}
+	}
+	if p.Rate > 0 {
+		if r, ok := o.rate(); ok && math.Abs(r-p.Rate) > p.Rate*_profileTolerance {
+			problems = append(problems, "rate "+strconv.FormatFloat(r, 'f', 1, 64)+" Hz, want "+strconv.FormatFloat(p.Rate, 'f', -1, 64)+" Hz")
+		}
+	}
+	for _, g := range slices.Sorted(maps.Keys(_gnss)) {
+		for _, t := range _gnss[g].talkers {
+			if p.GNSS != nil && !slices.Contains(p.GNSS, g) && o.talkers[t] {
+				problems = append(problems, "unexpected "+g+" satellites ["+t+"]")
+			}
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("%w [%s] [%s]", ErrProfile, dev.FileIO, strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// controls reports if the profile protocol can switch the sentence type
+func (p Profile) controls(typ string) bool {
+	if p.Protocol == ProtocolPMTK {
+		_, ok := _pmtkSentences[typ]
+		return ok
+	}
+	_, ok := _ubxSentences[typ]
+	return ok
+}
+
+// rate returns the measured epoch rate of the first epoch sentence seen [RMC, GGA, GNS]
+func (o *observer) rate() (float64, bool) {
+	for _, typ := range []string{"RMC", "GGA", "GNS"} {
+		if t := o.times[typ]; len(t) > 2 {
+			return float64(len(t)-1) / t[len(t)-1].Sub(t[0]).Seconds(), true
+		}
+	}
+	return 0, false
+}
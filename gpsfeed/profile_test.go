@@ -0,0 +1,57 @@
+package gpsfeed
+
+import (
+	"testing"
+	"time"
+
+	"paepcke.de/gpsinfo/ubx"
+)
+
+// TestObserve ...
+func TestObserve(t *testing.T) {
+	o := &observer{types: make(map[string]int), talkers: make(map[string]bool), times: make(map[string][]time.Time)}
+	rtcm := rtcmFrame([]byte{0x3E, 0xD0, 0x00, 0x03})
+	gsv := line("GLGSV,1,1,01,65,40,083,46")
+	for _, token := range []string{_rmc, _gga, gsv, _ubx, rtcm, "$GPRMC", "$", "", line("PMTK001,220,3"), "\xb5\x62$GPRMC,1"} {
+		if o.observe([]byte(token)) {
+			t.Errorf("observe(%q) matched", token)
+		}
+	}
+	if len(o.types) != 3 || o.types["RMC"] != 1 || o.types["GGA"] != 1 || o.types["GSV"] != 1 {
+		t.Errorf("types %v, want RMC GGA GSV once", o.types)
+	}
+	if len(o.talkers) != 1 || !o.talkers["GL"] {
+		t.Errorf("gsv talkers %v, want GL", o.talkers)
+	}
+}
+
+// TestMatchUBXAck ...
+func TestMatchUBXAck(t *testing.T) {
+	ack := func(id byte, payload ...byte) []byte {
+		return ubx.Encode(ubx.Frame{Class: ubx.ClassACK, ID: id, Payload: payload})
+	}
+	match := matchUBXAck(ubx.ClassCFG, 0x08)
+	tests := []struct {
+		token []byte
+		match bool
+		ack   bool
+	}{
+		{token: ack(ubx.IDAckAck, ubx.ClassCFG, 0x08), match: true, ack: true},
+		{token: ack(ubx.IDAckNak, ubx.ClassCFG, 0x08), match: true},
+		{token: ack(ubx.IDAckAck, ubx.ClassCFG, 0x01)},
+		{token: ack(ubx.IDAckAck, ubx.ClassCFG)},
+		{token: ack(0x02, ubx.ClassCFG, 0x08)},
+		{token: ubx.Encode(ubx.Frame{Class: ubx.ClassCFG, ID: 0x08, Payload: []byte{ubx.ClassCFG, 0x08}})},
+		{token: []byte(_ubx)},
+		{token: []byte(_rmc)},
+		{token: []byte{}},
+	}
+	for _, tt := range tests {
+		if got := match(tt.token); got != tt.match {
+			t.Errorf("matchUBXAck(%X) = %v, want %v", tt.token, got, tt.match)
+		}
+		if a, err := parseUBXAck(tt.token); tt.match && (err != nil || a.Ack != tt.ack) {
+			t.Errorf("parseUBXAck(%X) = %+v, %v, want ack %v", tt.token, a, err, tt.ack)
+		}
+	}
+}
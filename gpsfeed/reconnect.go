@@ -0,0 +1,68 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	_reconnectInitial    = time.Second
+	_reconnectMax        = time.Minute
+	_reconnectMultiplier = 2
+)
+
+//
+// Reconnect
+//
+
+// retryDelay returns the delay after the failed attempt n, failing for d, or giveUp once the policy is exhausted
+func retryDelay(dev *GpsDevice, n int, d time.Duration) (delay time.Duration, giveUp bool) {
+	p := dev.Reconnect
+	if p == nil {
+		return dev.dataValidTimeout() + time.Second, false // classic fixed delay
+	}
+	if (p.MaxAttempts > 0 && n >= p.MaxAttempts) || (p.MaxDuration > 0 && d >= p.MaxDuration) {
+		return 0, true
+	}
+	return p.jitter(p.delay(n)), false
+}
+
+// delay ...
+func (p *ReconnectPolicy) delay(n int) time.Duration {
+	initial, max, mult := p.Initial, p.Max, p.Multiplier
+	if initial <= 0 {
+		initial = _reconnectInitial
+	}
+	if max <= 0 {
+		max = _reconnectMax
+	}
+	if mult < 1 {
+		mult = _reconnectMultiplier
+	}
+	d := float64(initial)
+	for i := 1; i < n && d < float64(max); i++ {
+		d *= mult
+	}
+	if d > float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// jitter spreads d randomly by +/- Jitter
+func (p *ReconnectPolicy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+}
+
+// nextRetry ...
+func nextRetry(dev *GpsDevice) time.Time {
+	if t := dev.nextRetry.Load(); t != 0 {
+		return time.Unix(0, t)
+	}
+	return time.Time{}
+}
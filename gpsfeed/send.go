@@ -0,0 +1,183 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+	"paepcke.de/gpsinfo/ubx"
+)
+
+//
+// Send
+//
+
+const _crlf = "\r\n"
+
+// pmtk001 ack flags
+const (
+	_pmtkInvalid     = "0"
+	_pmtkUnsupported = "1"
+	_pmtkFailed      = "2"
+	_pmtkOK          = "3"
+)
+
+// formatCommand completes an nmea command with start char, checksum and line end, eg. PMTK220,100
+func formatCommand(cmd string) string {
+	cmd = strings.TrimRight(cmd, " \r\n")
+	if i := strings.Index(cmd, _checksep); i > -1 {
+		cmd = cmd[:i] // recalculate
+	}
+	cmd = strings.TrimLeft(cmd, "$")
+	return "$" + cmd + _checksep + nmeanano.Checksum(cmd) + _crlf
+}
+
+// write writes b to the device source, serialized by the write lock, that open and close take to
+// set and close the source. A read-only source or handle fails with ErrReadOnly.
+func write(dev *GpsDevice, b []byte) error {
+	dev.writeLock.Lock()
+	defer dev.writeLock.Unlock()
+	w, ok := dev.Source.(io.Writer)
+	if !ok {
+		return fmt.Errorf("%w [%s]", ErrReadOnly, dev.FileIO)
+	}
+	if _, err := w.Write(b); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return fmt.Errorf("%w [%s]", ErrReadOnly, dev.FileIO)
+		}
+		return fmt.Errorf("send [%s] [%w]", dev.FileIO, err)
+	}
+	return nil
+}
+
+// request registers match, writes cmd and waits for the first matching frame
+func request(dev *GpsDevice, cmd []byte, match Matcher, timeout time.Duration) ([]byte, error) {
+	if timeout <= 0 {
+		timeout = DeviceTimeout * time.Second
+	}
+	w := dev.waiters.add(match)
+	defer dev.waiters.remove(w)
+	if err := write(dev, cmd); err != nil {
+		return nil, err
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case token := <-w.ch:
+		return token, nil
+	case <-t.C:
+	}
+	return nil, fmt.Errorf("%w [%s] [%s]", ErrTimeout, dev.FileIO, bytes.TrimSpace(cmd))
+}
+
+// sendPMTK sends a mediatek command and checks the PMTK001 ack
+func sendPMTK(dev *GpsDevice, cmd string, timeout time.Duration) error {
+	cmd = formatCommand(cmd)
+	id := strings.TrimPrefix(cmd[1:strings.IndexAny(cmd, _sep+_checksep)], "PMTK")
+	resp, err := request(dev, []byte(cmd), MatchPrefix("$PMTK001,"+id+_sep), timeout)
+	if err != nil {
+		return err
+	}
+	fields := strings.Split(strings.SplitN(string(resp), _checksep, 2)[0], _sep)
+	switch fields[len(fields)-1] {
+	case _pmtkOK:
+		return nil
+	case _pmtkInvalid:
+		return fmt.Errorf("%w [%s] [PMTK%s invalid command]", ErrNak, dev.FileIO, id)
+	case _pmtkUnsupported:
+		return fmt.Errorf("%w [%s] [PMTK%s unsupported command]", ErrNak, dev.FileIO, id)
+	case _pmtkFailed:
+		return fmt.Errorf("%w [%s] [PMTK%s valid command, action failed]", ErrNak, dev.FileIO, id)
+	}
+	return fmt.Errorf("%w [%s] [unknown ack] [%s]", ErrNak, dev.FileIO, bytes.TrimSpace(resp))
+}
+
+// sendUBX sends a ubx message and checks the ACK-ACK / ACK-NAK
+func sendUBX(dev *GpsDevice, class, id byte, payload []byte, timeout time.Duration) error {
+	cmd := ubx.Encode(ubx.Frame{Class: class, ID: id, Payload: payload})
+	resp, err := request(dev, cmd, matchUBXAck(class, id), timeout)
+	if err != nil {
+		return err
+	}
+	if ack, err := parseUBXAck(resp); err == nil && ack.Ack {
+		return nil
+	}
+	return fmt.Errorf("%w [%s] [ubx %02X-%02X]", ErrNak, dev.FileIO, class, id)
+}
+
+// matchUBXAck matches ACK-ACK and ACK-NAK for the message class and id
+func matchUBXAck(class, id byte) Matcher {
+	return func(token []byte) bool {
+		ack, err := parseUBXAck(token)
+		return err == nil && ack.Class == class && ack.ID == id
+	}
+}
+
+// parseUBXAck decodes a ubx ACK-ACK or ACK-NAK token
+func parseUBXAck(token []byte) (ubx.Ack, error) {
+	if kindOf(token) != FrameUBX {
+		return ubx.Ack{}, fmt.Errorf("not a ubx frame")
+	}
+	f, err := ubx.ParseFrame(token)
+	if err != nil {
+		return ubx.Ack{}, err
+	}
+	return ubx.ParseAck(f)
+}
+
+//
+// Waiters
+//
+
+// waiter is a pending Request
+type waiter struct {
+	match Matcher
+	ch    chan []byte
+}
+
+// waiters holds the pending Requests of a device, fed by the device feed tap, the zero value is ready to use
+type waiters struct {
+	mu   sync.Mutex
+	list []*waiter
+}
+
+// add ...
+func (ws *waiters) add(match Matcher) *waiter {
+	w := &waiter{match: match, ch: make(chan []byte, 1)}
+	ws.mu.Lock()
+	ws.list = append(ws.list, w)
+	ws.mu.Unlock()
+	return w
+}
+
+// remove ...
+func (ws *waiters) remove(w *waiter) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for i := range ws.list {
+		if ws.list[i] == w {
+			ws.list = append(ws.list[:i], ws.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatch hands a copy of the token to the first pending matching waiter
+func (ws *waiters) dispatch(token []byte) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for i, w := range ws.list {
+		if w.match(token) {
+			w.ch <- bytes.Clone(token)
+			ws.list = append(ws.list[:i], ws.list[i+1:]...)
+			return
+		}
+	}
+}
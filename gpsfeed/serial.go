@@ -0,0 +1,169 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Serial Port
+//
+
+// Parity of a serial line
+type Parity byte
+
+// parity modes
+const (
+	ParityNone Parity = 'N'
+	ParityEven Parity = 'E'
+	ParityOdd  Parity = 'O'
+)
+
+// SerialConfig holds the serial line settings applied after every (re)open of the device.
+// Zero values keep the current settings of the port, RTSCTS and Raw are always applied.
+type SerialConfig struct {
+	Baud     int    // baud rate, eg. 4800, 9600, 115200
+	DataBits int    // data bits [5-8]
+	Parity   Parity // parity [N|E|O]
+	StopBits int    // stop bits [1|2]
+	RTSCTS   bool   // hardware flow control
+	Raw      bool   // raw mode, no line editing, echo or character translation
+}
+
+// String returns the settings in the notation accepted by ParseSerialConfig
+func (c SerialConfig) String() string {
+	s := strconv.Itoa(c.Baud)
+	if c.DataBits != 0 || c.Parity != 0 || c.StopBits != 0 {
+		s += "," + strconv.Itoa(c.DataBits) + string(c.Parity) + strconv.Itoa(c.StopBits)
+	}
+	if c.RTSCTS {
+		s += ",rtscts"
+	}
+	if c.Raw {
+		s += ",raw"
+	}
+	return s
+}
+
+// ParseSerialConfig parses serial line settings, eg. [115200], [9600,8N1], [115200,8N1,rtscts,raw]
+func ParseSerialConfig(s string) (SerialConfig, error) {
+	var c SerialConfig
+	var err error
+	for i, opt := range strings.Split(s, ",") {
+		switch {
+		case i == 0:
+			if c.Baud, err = strconv.Atoi(opt); err != nil || c.Baud <= 0 {
+				return SerialConfig{}, fmt.Errorf("invalid baud rate [%s]", opt)
+			}
+		case opt == "rtscts":
+			c.RTSCTS = true
+		case opt == "raw":
+			c.Raw = true
+		case len(opt) == 3:
+			c.DataBits, c.Parity, c.StopBits = int(opt[0]-'0'), Parity(strings.ToUpper(opt[1:2])[0]), int(opt[2]-'0')
+		default:
+			return SerialConfig{}, fmt.Errorf("invalid serial option [%s]", opt)
+		}
+	}
+	return c, c.validate()
+}
+
+// validate ...
+func (c SerialConfig) validate() error {
+	switch {
+	case c.DataBits != 0 && (c.DataBits < 5 || c.DataBits > 8):
+		return fmt.Errorf("invalid data bits [%d]", c.DataBits)
+	case c.Parity != 0 && c.Parity != ParityNone && c.Parity != ParityEven && c.Parity != ParityOdd:
+		return fmt.Errorf("invalid parity [%c]", c.Parity)
+	case c.StopBits != 0 && c.StopBits != 1 && c.StopBits != 2:
+		return fmt.Errorf("invalid stop bits [%d]", c.StopBits)
+	}
+	return nil
+}
+
+//
+// Autobaud
+//
+
+// _autoBaudMinValid is the minimum number of valid sentences for a rate to qualify
+const _autoBaudMinValid = 2
+
+// autoBaud probes all BaudRates and locks the device serial settings onto the best scoring one
+func autoBaud(ctx context.Context, dev *GpsDevice, window time.Duration) (int, error) {
+	if window <= 0 {
+		window = _autoBaudWindow
+	}
+	if dev.FileIO == _stdin || isNetwork(dev.FileIO) {
+		return 0, fmt.Errorf("autobaud: not a serial device [%s]", dev.FileIO)
+	}
+	dev.Lock.Lock()
+	defer dev.Lock.Unlock()
+	var (
+		best      int
+		bestScore float64
+		bestValid int
+	)
+	for _, baud := range BaudRates {
+		valid, total, err := probeBaud(ctx, dev, baud, window)
+		if err != nil {
+			return 0, err
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		score := 0.0
+		if total > 0 {
+			score = float64(valid) / float64(total)
+		}
+		emit(dev, Event{Kind: EventBaudProbe, Baud: baud, Detail: "[score " + strconv.FormatFloat(score, 'f', 2, 64) + "] [" + strconv.Itoa(valid) + "/" + strconv.Itoa(total) + "]"})
+		if valid >= _autoBaudMinValid && (score > bestScore || score == bestScore && valid > bestValid) {
+			best, bestScore, bestValid = baud, score, valid
+		}
+	}
+	if best == 0 {
+		emit(dev, Event{Kind: EventBaudFailed})
+		return 0, fmt.Errorf("autobaud: no valid nmea data at any baud rate [%s]", dev.FileIO)
+	}
+	if dev.Serial == nil {
+		dev.Serial = &SerialConfig{Raw: true}
+	}
+	dev.Serial.Baud = best
+	emit(dev, Event{Kind: EventBaudLocked, Baud: best})
+	return best, nil
+}
+
+// probeBaud reads from the device at the given rate for the time window or until ctx is done, counts valid
+// and total nmea sentences. The device is opened like openDev does, usb specs are resolved.
+func probeBaud(ctx context.Context, dev *GpsDevice, baud int, window time.Duration) (valid, total int, err error) {
+	c := SerialConfig{Baud: baud, Raw: true}
+	if dev.Serial != nil {
+		c = *dev.Serial
+		c.Baud = baud
+	}
+	src := newSource(dev.FileIO, &c)
+	if err := src.Open(); err != nil {
+		return 0, 0, err
+	}
+	defer src.Close()
+	timer := time.AfterFunc(window, func() { src.Close() }) // unblock the probe once the window is over
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, func() { src.Close() }) // or ctx is done
+	defer stop()
+	feed := newScanner(src, nil, nil)
+	for feed.Scan() {
+		line := feed.Text()
+		if kindOf(feed.Bytes()) != FrameNMEA || line[0] == '\\' {
+			continue
+		}
+		total++
+		if checkSumValid(line) {
+			valid++
+		}
+	}
+	return valid, total, nil
+}
@@ -0,0 +1,133 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || loong64)
+
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// termios [asm-generic]
+const (
+	_tcgets  = 0x5401
+	_tcsets  = 0x5402
+	_cbaud   = 0x100F
+	_csize   = 0x30
+	_cstopb  = 0x40
+	_cread   = 0x80
+	_parenb  = 0x100
+	_parodd  = 0x200
+	_clocal  = 0x800
+	_crtscts = 0x80000000
+	_vtime   = 5
+	_vmin    = 6
+)
+
+// _bauds ...
+var _bauds = map[int]uint32{
+	1200:   0x9,
+	2400:   0xB,
+	4800:   0xC,
+	9600:   0xD,
+	19200:  0xE,
+	38400:  0xF,
+	57600:  0x1001,
+	115200: 0x1002,
+	230400: 0x1003,
+	460800: 0x1004,
+	921600: 0x1007,
+}
+
+// _dataBits ...
+var _dataBits = map[int]uint32{5: 0x0, 6: 0x10, 7: 0x20, 8: 0x30}
+
+// openFile opens the device, without becoming its controlling terminal, read-only if it can not be written
+func openFile(name string) (f *os.File, writable bool, err error) {
+	if fi, err := os.Stat(name); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		if f, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0); err == nil {
+			return f, true, nil // writable device, see Send
+		}
+	}
+	f, err = os.OpenFile(name, os.O_RDONLY|syscall.O_NOCTTY, 0)
+	return f, false, err
+}
+
+// configure applies the serial line settings via termios ioctls
+func configure(f *os.File, c *SerialConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	var t syscall.Termios
+	if err := ioctl(f, _tcgets, &t); err != nil {
+		return fmt.Errorf("not a serial device [%w]", err)
+	}
+	if err := termios(&t, c); err != nil {
+		return err
+	}
+	return ioctl(f, _tcsets, &t)
+}
+
+// termios applies the serial line settings to t
+func termios(t *syscall.Termios, c *SerialConfig) error {
+	if c.Baud != 0 {
+		speed, ok := _bauds[c.Baud]
+		if !ok {
+			return fmt.Errorf("unsupported baud rate [%d]", c.Baud)
+		}
+		t.Cflag = t.Cflag&^_cbaud | speed
+		t.Ispeed, t.Ospeed = uint32(c.Baud), uint32(c.Baud)
+	}
+	if c.Raw {
+		t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON | syscall.IXOFF
+		t.Oflag &^= syscall.OPOST
+		t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		t.Cflag = t.Cflag&^(_csize|_parenb) | _dataBits[8]
+		t.Cc[_vmin], t.Cc[_vtime] = 1, 0
+	}
+	if c.DataBits != 0 {
+		t.Cflag = t.Cflag&^_csize | _dataBits[c.DataBits]
+	}
+	switch c.Parity {
+	case ParityNone:
+		t.Cflag &^= _parenb | _parodd
+	case ParityEven:
+		t.Cflag = t.Cflag&^_parodd | _parenb
+	case ParityOdd:
+		t.Cflag |= _parenb | _parodd
+	}
+	switch c.StopBits {
+	case 1:
+		t.Cflag &^= _cstopb
+	case 2:
+		t.Cflag |= _cstopb
+	}
+	if c.RTSCTS {
+		t.Cflag |= _crtscts
+	} else {
+		t.Cflag &^= _crtscts
+	}
+	t.Cflag |= _cread | _clocal
+	return nil
+}
+
+// ioctl ...
+func ioctl(f *os.File, req uintptr, t *syscall.Termios) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(t)))
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
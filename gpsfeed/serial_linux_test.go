@@ -0,0 +1,306 @@
+//go:build linux && (386 || amd64 || arm || arm64 || riscv64 || loong64)
+
+package gpsfeed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// pty ioctls
+const (
+	_tiocgptn   = 0x80045430
+	_tiocsptlck = 0x40045431
+)
+
+// openPTY returns the master and the slave of a new pseudo terminal, skips if there is none
+func openPTY(t *testing.T) (master, slave *os.File) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pty support [%v]", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	var n, unlock uint32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), _tiocsptlck, uintptr(unsafe.Pointer(&unlock))); errno != 0 {
+		t.Skipf("pty unlock [%v]", errno)
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, master.Fd(), _tiocgptn, uintptr(unsafe.Pointer(&n))); errno != 0 {
+		t.Skipf("pty number [%v]", errno)
+	}
+	if slave, _, err = openFile("/dev/pts/" + strconv.Itoa(int(n))); err != nil {
+		t.Skipf("pty slave [%v]", err)
+	}
+	t.Cleanup(func() { slave.Close() })
+	return master, slave
+}
+
+// _serialTests ...
+var _serialTests = []struct {
+	config   string
+	speed    uint32
+	size     uint32
+	parity   uint32
+	stopBits uint32
+	rtscts   bool
+	raw      bool
+}{
+	{config: "9600,8N1,raw", speed: 0xD, size: 0x30, raw: true},
+	{config: "115200,7E2", speed: 0x1002, size: 0x20, parity: _parenb, stopBits: _cstopb},
+	{config: "4800,8O1,rtscts", speed: 0xC, size: 0x30, parity: _parenb | _parodd, rtscts: true},
+	{config: "57600,5N2,raw", speed: 0x1001, size: 0x0, stopBits: _cstopb, raw: true},
+	{config: "921600,6N1,rtscts,raw", speed: 0x1007, size: 0x10, rtscts: true, raw: true},
+}
+
+// cooked returns line discipline defaults, as left behind by a terminal
+func cooked() syscall.Termios {
+	return syscall.Termios{
+		Iflag: syscall.ICRNL | syscall.IXON,
+		Oflag: syscall.OPOST,
+		Lflag: syscall.ICANON | syscall.ECHO | syscall.ISIG,
+		Cflag: _parenb | _crtscts | _cstopb | 0x30,
+	}
+}
+
+// isRaw ...
+func isRaw(t syscall.Termios) bool {
+	return t.Lflag&(syscall.ICANON|syscall.ECHO|syscall.ISIG) == 0 && t.Oflag&syscall.OPOST == 0 &&
+		t.Iflag&(syscall.ICRNL|syscall.IXON) == 0 && t.Cc[_vmin] == 1 && t.Cc[_vtime] == 0
+}
+
+// TestTermios checks all line settings, including data bits and parity a pty does not keep
+func TestTermios(t *testing.T) {
+	for _, tt := range _serialTests {
+		c, err := ParseSerialConfig(tt.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := cooked()
+		if err := termios(&got, &c); err != nil {
+			t.Fatalf("termios [%s]: %v", tt.config, err)
+		}
+		if got.Cflag&_cbaud != tt.speed || got.Ispeed != uint32(c.Baud) || got.Ospeed != uint32(c.Baud) {
+			t.Errorf("[%s] baud %#x [%d/%d], want %#x", tt.config, got.Cflag&_cbaud, got.Ispeed, got.Ospeed, tt.speed)
+		}
+		if got.Cflag&_csize != tt.size {
+			t.Errorf("[%s] data bits %#x, want %#x", tt.config, got.Cflag&_csize, tt.size)
+		}
+		if got.Cflag&(_parenb|_parodd) != tt.parity {
+			t.Errorf("[%s] parity %#x, want %#x", tt.config, got.Cflag&(_parenb|_parodd), tt.parity)
+		}
+		if got.Cflag&_cstopb != tt.stopBits {
+			t.Errorf("[%s] stop bits %#x, want %#x", tt.config, got.Cflag&_cstopb, tt.stopBits)
+		}
+		if got.Cflag&_crtscts != 0 != tt.rtscts {
+			t.Errorf("[%s] rtscts %v, want %v", tt.config, got.Cflag&_crtscts != 0, tt.rtscts)
+		}
+		if got.Cflag&(_cread|_clocal) != _cread|_clocal {
+			t.Errorf("[%s] cread|clocal not set [%#x]", tt.config, got.Cflag)
+		}
+		if isRaw(got) != tt.raw {
+			t.Errorf("[%s] raw %v, want %v", tt.config, isRaw(got), tt.raw)
+		}
+	}
+}
+
+// TestConfigure applies the settings to a pty and reads them back via TCGETS. The linux pty driver
+// forces 8 data bits without parity, both are covered by TestTermios.
+func TestConfigure(t *testing.T) {
+	_, slave := openPTY(t)
+	for _, tt := range _serialTests {
+		reset := cooked()
+		if err := ioctl(slave, _tcsets, &reset); err != nil {
+			t.Fatal(err)
+		}
+		c, err := ParseSerialConfig(tt.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := configure(slave, &c); err != nil {
+			t.Fatalf("configure [%s]: %v", tt.config, err)
+		}
+		var got syscall.Termios
+		if err := ioctl(slave, _tcgets, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Cflag&_cbaud != tt.speed {
+			t.Errorf("[%s] baud %#x, want %#x", tt.config, got.Cflag&_cbaud, tt.speed)
+		}
+		if got.Cflag&_cstopb != tt.stopBits {
+			t.Errorf("[%s] stop bits %#x, want %#x", tt.config, got.Cflag&_cstopb, tt.stopBits)
+		}
+		if got.Cflag&_crtscts != 0 != tt.rtscts {
+			t.Errorf("[%s] rtscts %v, want %v", tt.config, got.Cflag&_crtscts != 0, tt.rtscts)
+		}
+		if isRaw(got) != tt.raw {
+			t.Errorf("[%s] raw %v, want %v [iflag %#x oflag %#x lflag %#x]", tt.config, isRaw(got), tt.raw, got.Iflag, got.Oflag, got.Lflag)
+		}
+	}
+}
+
+// TestConfigureErrors ...
+func TestConfigureErrors(t *testing.T) {
+	_, slave := openPTY(t)
+	if err := configure(slave, &SerialConfig{Baud: 12345}); err == nil {
+		t.Error("unsupported baud rate accepted")
+	}
+	if err := configure(slave, &SerialConfig{DataBits: 9}); err == nil {
+		t.Error("invalid data bits accepted")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := configure(f, &SerialConfig{Baud: 9600}); err == nil {
+		t.Error("regular file accepted as serial device")
+	}
+}
+
+// TestAutoBaudContext ...
+func TestAutoBaudContext(t *testing.T) {
+	_, slave := openPTY(t)
+	dev := &GpsDevice{FileIO: slave.Name(), Handler: HandlerFunc(func(Event) {})}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := dev.AutoBaudContext(ctx, time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AutoBaudContext = %v, want context.DeadlineExceeded", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("AutoBaudContext returned after %v, want prompt return once ctx is done", d)
+	}
+}
+
+// TestAutoBaudEvents ...
+func TestAutoBaudEvents(t *testing.T) {
+	master, slave := openPTY(t)
+	rates := BaudRates
+	BaudRates = []int{4800, 9600}
+	defer func() { BaudRates = rates }()
+	events := make(chan Event, 10)
+	dev := &GpsDevice{FileIO: slave.Name(), Events: events}
+	done := make(chan struct{})
+	defer close(done)
+	go func() { // emit valid nmea at any rate, a pty has no line speed
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				master.Write([]byte(_rmc + "\r\n"))
+			}
+		}
+	}()
+	baud, err := dev.AutoBaudContext(context.Background(), 200*time.Millisecond)
+	if err != nil || !slices.Contains(BaudRates, baud) {
+		t.Fatalf("AutoBaudContext = %d, %v", baud, err)
+	}
+	close(events)
+	var kinds []EventKind
+	for e := range events {
+		kinds = append(kinds, e.Kind)
+		if e.Baud == 0 {
+			t.Errorf("%s event without baud rate", e.Kind)
+		}
+		if e.Kind == EventBaudLocked && e.Baud != baud {
+			t.Errorf("locked %d, AutoBaud returned %d", e.Baud, baud)
+		}
+	}
+	if want := []EventKind{EventBaudProbe, EventBaudProbe, EventBaudLocked}; !slices.Equal(kinds, want) {
+		t.Errorf("events %v, want %v", kinds, want)
+	}
+}
+
+// TestAutoBaudSources checks that autobaud resolves device specs like open does
+func TestAutoBaudSources(t *testing.T) {
+	ctx := context.Background()
+	dev := &GpsDevice{FileIO: "usb:ffff:fffe", Handler: HandlerFunc(func(Event) {})}
+	if _, err := dev.AutoBaudContext(ctx, 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "no usb tty device found") {
+		t.Errorf("AutoBaudContext(usb spec) = %v, want usb resolve error", err)
+	}
+	for _, name := range []string{"-", "tcp://localhost:2947"} {
+		dev := &GpsDevice{FileIO: name, Handler: HandlerFunc(func(Event) {})}
+		if _, err := dev.AutoBaudContext(ctx, 10*time.Millisecond); err == nil || !strings.Contains(err.Error(), "not a serial device") {
+			t.Errorf("AutoBaudContext(%s) = %v, want not a serial device", name, err)
+		}
+	}
+}
+
+// TestProbeDeviceQuery checks that discovery asks a silent mediatek receiver for its release
+func TestProbeDeviceQuery(t *testing.T) {
+	master, slave := openPTY(t)
+	go func() { // answer the query only, like most mtk modules
+		feed := newScanner(master, nil, nil)
+		for feed.Scan() {
+			if strings.HasPrefix(feed.Text(), "$"+_pmtkRelease+_checksep) {
+				master.Write([]byte(line("PMTK705,AXN_5.1.7_3333_19020118,0027,Quectel-L76,1.0") + "\r\n"))
+			}
+		}
+	}()
+	d := &Discovery{Device: slave.Name()}
+	probeDevice(context.Background(), d, 300*time.Millisecond)
+	if want := "MediaTek Quectel-L76 [AXN_5.1.7_3333_19020118]"; d.Model != want || d.Err != nil {
+		t.Errorf("Model = %q, %v, want %q", d.Model, d.Err, want)
+	}
+}
+
+// TestBaudFallback switches to the profile rate, falls back to the configured rate once no valid data
+// follows and re-applies the profile without switching again
+func TestBaudFallback(t *testing.T) {
+	_, slave := openPTY(t)
+	events := make(chan Event, 100)
+	dev := &GpsDevice{
+		FileIO:            slave.Name(),
+		Serial:            &SerialConfig{Baud: 9600, Raw: true},
+		Profile:           &Profile{Protocol: ProtocolPMTK, Baud: 115200},
+		Events:            events,
+		ResponsiveTimeout: 10 * time.Second,
+		DataValidTimeout:  100 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() { // consume and reopen, like a Manager
+		defer close(done)
+		for dev.OpenContext(ctx) == nil {
+			for dev.Feed.Scan() {
+			}
+			dev.Close()
+		}
+	}()
+	var rates []int
+	for {
+		var e Event
+		select {
+		case e = <-events:
+		case <-ctx.Done():
+			t.Fatalf("no fallback, opened at %v", rates)
+		}
+		switch e.Kind {
+		case EventOpened:
+			rates = append(rates, e.Baud)
+		case EventProfileError:
+			if !errors.Is(e.Err, ErrProfile) || !strings.Contains(e.Err.Error(), "no valid data at 115200 baud, kept 9600") {
+				t.Errorf("profile error %v, want no valid data at 115200", e.Err)
+			}
+			cancel()
+			<-done
+			if want := []int{9600, 115200, 9600}; !slices.Equal(rates, want) {
+				t.Errorf("opened at %v, want %v", rates, want)
+			}
+			if dev.Serial.Baud != 9600 {
+				t.Errorf("Serial.Baud = %d, want the configured 9600", dev.Serial.Baud)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux || !(386 || amd64 || arm || arm64 || riscv64 || loong64)
+
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"fmt"
+	"os"
+)
+
+// openFile ...
+func openFile(name string) (f *os.File, writable bool, err error) {
+	if fi, err := os.Stat(name); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		if f, err := os.OpenFile(name, os.O_RDWR, 0); err == nil {
+			return f, true, nil // writable device, see Send
+		}
+	}
+	f, err = os.Open(name)
+	return f, false, err
+}
+
+// configure ...
+func configure(_ *os.File, _ *SerialConfig) error {
+	return fmt.Errorf("serial port configuration is not supported on this platform")
+}
@@ -0,0 +1,262 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+//
+// Sources
+//
+
+// newSource returns the source for a device name: "-" is stdin, network urls and device files
+func newSource(name string, serial *SerialConfig) Source {
+	switch {
+	case name == _stdin:
+		return NewStdinSource()
+	case isNetwork(name):
+		return &netSource{url: name}
+	case isUSB(name):
+		m, err := parseUSB(name)
+		return &usbSource{fileSource: fileSource{serial: serial}, spec: name, match: m, err: err}
+	}
+	return &fileSource{path: name, serial: serial}
+}
+
+// handle guards the reader of a reopenable source
+type handle struct {
+	mu sync.Mutex
+	rc io.ReadCloser
+}
+
+// set ...
+func (h *handle) set(rc io.ReadCloser) {
+	h.mu.Lock()
+	h.rc = rc
+	h.mu.Unlock()
+}
+
+// Read fails if the source is not open
+func (h *handle) Read(p []byte) (int, error) {
+	h.mu.Lock()
+	rc := h.rc
+	h.mu.Unlock()
+	if rc == nil {
+		return 0, os.ErrClosed
+	}
+	return rc.Read(p)
+}
+
+// Write fails if the source is not open or not writable
+func (h *handle) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	rc := h.rc
+	h.mu.Unlock()
+	if rc == nil {
+		return 0, os.ErrClosed
+	}
+	w, ok := rc.(io.Writer)
+	if !ok {
+		return 0, ErrReadOnly
+	}
+	return w.Write(p)
+}
+
+// Close closes the current reader, unblocks pending reads
+func (h *handle) Close() error {
+	h.mu.Lock()
+	rc := h.rc
+	h.rc = nil
+	h.mu.Unlock()
+	if rc == nil {
+		return nil
+	}
+	return rc.Close()
+}
+
+// fileSource ...
+type fileSource struct {
+	handle
+	path   string
+	serial *SerialConfig
+}
+
+// Open opens the file or device and applies the serial settings, if any
+func (s *fileSource) Open() error {
+	file, writable, err := openFile(s.path)
+	if err != nil {
+		return err
+	}
+	if s.serial != nil {
+		if err := configure(file, s.serial); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if !writable {
+		s.set(readOnly{file})
+		return nil
+	}
+	s.set(file)
+	return nil
+}
+
+// Name ...
+func (s *fileSource) Name() string { return s.path }
+
+// readOnly hides the Write of a read-only file, Send fails with ErrReadOnly instead of EBADF
+type readOnly struct{ io.ReadCloser }
+
+// netSource ...
+type netSource struct {
+	handle
+	url string
+}
+
+// Open connects, accepts or listens
+func (s *netSource) Open() error {
+	conn, err := openNetwork(s.url)
+	if err != nil {
+		return err
+	}
+	s.set(conn)
+	return nil
+}
+
+// Name ...
+func (s *netSource) Name() string { return s.url }
+
+// readerSource wraps an already opened reader [stdin, pipes]. A pump goroutine drains the reader, so
+// Close unblocks a pending Read and a reopen resumes the stream, until the reader ends.
+type readerSource struct {
+	name    string
+	r       io.Reader
+	once    sync.Once
+	chunks  chan []byte   // pump output
+	done    chan struct{} // closed once the reader ended
+	err     error         // reader end, valid once done is closed
+	mu      sync.Mutex
+	open    chan struct{} // closed on Close [nil: not open]
+	pending []byte        // unread rest of the last chunk
+}
+
+// pump reads until the reader ends, the chunks are handed over one at a time
+func (s *readerSource) pump() {
+	for {
+		buf := make([]byte, 4096)
+		n, err := s.r.Read(buf)
+		if n > 0 {
+			s.chunks <- buf[:n]
+		}
+		if err != nil {
+			s.err = err
+			close(s.done)
+			return
+		}
+	}
+}
+
+// Open starts the pump on first use, fails with ErrSourceEnded once the reader ended
+func (s *readerSource) Open() error {
+	s.once.Do(func() {
+		s.chunks, s.done = make(chan []byte), make(chan struct{})
+		go s.pump()
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.done:
+		if len(s.pending) == 0 {
+			return fmt.Errorf("%w [%s] [%w]", ErrSourceEnded, s.name, s.err)
+		}
+	default:
+	}
+	if s.open == nil {
+		s.open = make(chan struct{})
+	}
+	return nil
+}
+
+// Read returns os.ErrClosed once the source got closed, the reader end error at the end of the stream
+func (s *readerSource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	open := s.open
+	if open != nil && len(s.pending) > 0 {
+		n := copy(p, s.pending)
+		s.pending = s.pending[n:]
+		s.mu.Unlock()
+		return n, nil
+	}
+	s.mu.Unlock()
+	if open == nil {
+		return 0, os.ErrClosed
+	}
+	select {
+	case b := <-s.chunks:
+		n := copy(p, b)
+		s.mu.Lock()
+		s.pending = b[n:]
+		s.mu.Unlock()
+		return n, nil
+	case <-s.done:
+		return 0, s.err
+	case <-open:
+		return 0, os.ErrClosed
+	}
+}
+
+// Close unblocks a pending Read, the underlying reader is left open for a reopen
+func (s *readerSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.open != nil {
+		close(s.open)
+		s.open = nil
+	}
+	return nil
+}
+
+// Name ...
+func (s *readerSource) Name() string { return s.name }
+
+// memorySource replays a fixed buffer, every Open rewinds
+type memorySource struct {
+	name string
+	data []byte
+	mu   sync.Mutex
+	r    *bytes.Reader
+}
+
+// Open rewinds to the start of the buffer
+func (s *memorySource) Open() error {
+	s.mu.Lock()
+	s.r = bytes.NewReader(s.data)
+	s.mu.Unlock()
+	return nil
+}
+
+// Read ...
+func (s *memorySource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.r == nil {
+		return 0, os.ErrClosed
+	}
+	return s.r.Read(p)
+}
+
+// Close ...
+func (s *memorySource) Close() error {
+	s.mu.Lock()
+	s.r = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// Name ...
+func (s *memorySource) Name() string { return s.name }
@@ -0,0 +1,209 @@
+package gpsfeed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventLog collects device events
+type eventLog struct {
+	mu     sync.Mutex
+	events []EventKind
+}
+
+// Handle ...
+func (l *eventLog) Handle(e Event) {
+	l.mu.Lock()
+	l.events = append(l.events, e.Kind)
+	l.mu.Unlock()
+}
+
+// has ...
+func (l *eventLog) has(k EventKind) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.events {
+		if e == k {
+			return true
+		}
+	}
+	return false
+}
+
+// consume scans the device feed like gpsinfo does, the channel gets every token and is closed once the feed ends
+func consume(dev *GpsDevice) chan string {
+	tokens := make(chan string, 10)
+	go func() {
+		defer close(tokens)
+		for dev.Feed.Scan() {
+			dev.Responsive.Store(true)
+			dev.DataValid.Store(true)
+			tokens <- dev.Feed.Text()
+		}
+	}()
+	return tokens
+}
+
+// next returns the next token, fails after timeout
+func next(t *testing.T, tokens chan string) (string, bool) {
+	t.Helper()
+	select {
+	case s, ok := <-tokens:
+		return s, ok
+	case <-time.After(3 * time.Second):
+		t.Fatal("feed blocked")
+	}
+	return "", false
+}
+
+// TestReaderSourceWatchdog ...
+func TestReaderSourceWatchdog(t *testing.T) {
+	pr, pw := io.Pipe()
+	events := &eventLog{}
+	dev := &GpsDevice{
+		Source:            NewPipeSource("pipe", pr),
+		Handler:           events,
+		ResponsiveTimeout: 100 * time.Millisecond,
+		DataValidTimeout:  150 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// data gap longer than the watchdog timeout: the trip has to unblock the pending read
+	if err := dev.OpenContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tokens := consume(dev)
+	go pw.Write([]byte(_rmc + "\n"))
+	if s, _ := next(t, tokens); s != _rmc {
+		t.Fatalf("token %q, want %q", s, _rmc)
+	}
+	if _, ok := next(t, tokens); ok {
+		t.Fatal("feed continued without data")
+	}
+	if !events.has(EventUnresponsive) && !events.has(EventInvalidData) {
+		t.Errorf("no watchdog trip event [%v]", events.events)
+	}
+	dev.Close()
+
+	// reopen resumes the stream
+	if err := dev.OpenContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tokens = consume(dev)
+	go pw.Write([]byte(_gga + "\n"))
+	if s, _ := next(t, tokens); s != _gga {
+		t.Fatalf("token %q after reopen, want %q", s, _gga)
+	}
+
+	// end of stream is permanent, no retry loop
+	pw.Close()
+	for ok := true; ok; _, ok = next(t, tokens) {
+	}
+	dev.Close()
+	start := time.Now()
+	err := dev.OpenContext(ctx)
+	if !errors.Is(err, ErrGiveUp) || !errors.Is(err, ErrSourceEnded) {
+		t.Fatalf("reopen after end = %v, want ErrGiveUp and ErrSourceEnded", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("give up took %v, want no retry delay", time.Since(start))
+	}
+	if !events.has(EventGiveUp) {
+		t.Errorf("no give up event [%v]", events.events)
+	}
+}
+
+// TestReaderSourceClose ...
+func TestReaderSourceClose(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	s := NewPipeSource("pipe", pr)
+	if _, err := s.Read(make([]byte, 8)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("read before open = %v, want os.ErrClosed", err)
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Read(make([]byte, 8))
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	s.Close()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, os.ErrClosed) {
+			t.Errorf("pending read = %v, want os.ErrClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not unblock the pending read")
+	}
+
+	// partial reads keep the rest of a chunk
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	go pw.Write([]byte("0123456789"))
+	buf := make([]byte, 4)
+	var got []byte
+	for len(got) < 10 {
+		n, err := s.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, buf[:n]...)
+	}
+	if string(got) != "0123456789" {
+		t.Errorf("read %q", got)
+	}
+}
+
+// TestSendReadOnly checks that Send maps read-only sources and handles to ErrReadOnly, also while
+// the device is closed and reopened concurrently
+func TestSendReadOnly(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "gps")
+	if err := os.WriteFile(name, []byte(strings.Repeat(_rmc+"\n", 100)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		dev  *GpsDevice
+	}{
+		{name: "file", dev: &GpsDevice{FileIO: name}},
+		{name: "pipe", dev: &GpsDevice{Source: NewPipeSource("pipe", strings.NewReader(_rmc+"\n"))}},
+	}
+	for _, tt := range tests {
+		tt.dev.Handler = HandlerFunc(func(Event) {})
+		done := make(chan struct{})
+		go func() { // open and close concurrently to Send
+			defer close(done)
+			for i := 0; i < 20; i++ {
+				if tt.dev.OpenContext(context.Background()) != nil {
+					return
+				}
+				tt.dev.Close()
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			if err := tt.dev.Send("PMTK605"); !errors.Is(err, ErrReadOnly) && !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("%s: Send = %v, want ErrReadOnly", tt.name, err)
+			}
+		}
+		<-done
+		if err := tt.dev.OpenContext(context.Background()); err == nil {
+			if err := tt.dev.Send("PMTK605"); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("%s: Send on open device = %v, want ErrReadOnly", tt.name, err)
+			}
+			tt.dev.Close()
+		}
+	}
+}
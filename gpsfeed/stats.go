@@ -0,0 +1,145 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Statistics
+//
+
+// statKey ...
+type statKey struct{ talker, typ string }
+
+// _maxStatKeys caps the talker+type counters of a device, beyond the cap everything counts as other
+const _maxStatKeys = 256
+
+// _statOther collects checksum and parse failures of unseen talker+types, malformed addresses and
+// everything beyond the cap, corrupt lines must not grow the counters
+var _statOther = statKey{talker: "", typ: "OTHER"}
+
+// counter holds the running counters of a single talker+type
+type counter struct {
+	SentenceStats
+	first     time.Time
+	intervals uint64
+	mean      float64 // average interval [ns]
+	jitter    float64 // mean absolute interval deviation [ns]
+}
+
+// sentenceStats collects the per talker+type counters of a device, the zero value is ready to use
+type sentenceStats struct {
+	mu       sync.Mutex
+	counters map[statKey]*counter
+}
+
+// record counts a sentence or frame of talker+type received at t
+func (s *sentenceStats) record(talker, typ string, o Outcome, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counters == nil {
+		s.counters = make(map[statKey]*counter)
+	}
+	key := statKey{talker, typ}
+	c, ok := s.counters[key]
+	if !ok && (o == OutcomeChecksum || o == OutcomeParse || !key.valid() || len(s.counters) >= _maxStatKeys) {
+		key = _statOther
+		c, ok = s.counters[key]
+	}
+	if !ok {
+		c = &counter{SentenceStats: SentenceStats{Talker: key.talker, Type: key.typ}, first: t}
+		s.counters[key] = c
+	}
+	if c.Count > 0 {
+		d := float64(t.Sub(c.Last))
+		c.intervals++
+		c.mean += (d - c.mean) / float64(c.intervals)
+		dev := d - c.mean
+		if dev < 0 {
+			dev = -dev
+		}
+		c.jitter += (dev - c.jitter) / float64(c.intervals)
+	}
+	c.Count++
+	c.Last = t
+	switch o {
+	case OutcomeChecksum:
+		c.Checksum++
+	case OutcomeParse:
+		c.Parse++
+	case OutcomeUnsupported:
+		c.Unsupported++
+	}
+}
+
+// valid reports if talker and type are upper case letters and digits only, with a type
+func (k statKey) valid() bool {
+	if k.typ == "" {
+		return false
+	}
+	for _, r := range k.talker + k.typ {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshot returns all counters, ordered by talker and type
+func (s *sentenceStats) snapshot() []SentenceStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]SentenceStats, 0, len(s.counters))
+	for _, c := range s.counters {
+		st := c.SentenceStats
+		st.Interval = time.Duration(c.mean)
+		st.Jitter = time.Duration(c.jitter)
+		if span := c.Last.Sub(c.first).Seconds(); span > 0 {
+			st.Rate = float64(c.Count-1) / span
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Talker != list[j].Talker {
+			return list[i].Talker < list[j].Talker
+		}
+		return list[i].Type < list[j].Type
+	})
+	return list
+}
+
+// reset ...
+func (s *sentenceStats) reset() {
+	s.mu.Lock()
+	s.counters = nil
+	s.mu.Unlock()
+}
+
+// addressOf splits the address field of an nmea sentence into talker and type, proprietary
+// sentences have talker P, eg. $GPRMC [GP, RMC], $PUBX [P, UBX], \tag block\!AIVDM [AI, VDM]
+func addressOf(sentence string) (talker, typ string) {
+	if i := strings.LastIndexByte(sentence, '\\'); i > -1 {
+		sentence = sentence[i+1:]
+	}
+	if len(sentence) < 2 || (sentence[0] != '$' && sentence[0] != '!') {
+		return "", ""
+	}
+	address := sentence[1:]
+	if i := strings.IndexAny(address, _sep+_checksep); i > -1 {
+		address = address[:i]
+	}
+	switch {
+	case address == "":
+		return "", "" // malformed, eg. $,GPRMC or $*00
+	case address[0] == 'P':
+		return "P", address[1:]
+	case len(address) < 3:
+		return "", address
+	}
+	return address[:2], address[2:]
+}
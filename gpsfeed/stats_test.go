@@ -0,0 +1,113 @@
+package gpsfeed
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestAddressOf ...
+func TestAddressOf(t *testing.T) {
+	tests := []struct {
+		in, talker, typ string
+	}{
+		{in: _rmc, talker: "GP", typ: "RMC"},
+		{in: "!AIVDM,1,1,,A,13aG?P0P00PD;88MD5MTDww@2<0L,0*23", talker: "AI", typ: "VDM"},
+		{in: "$PMTK001,220,3*30", talker: "P", typ: "MTK001"},
+		{in: `\s:SRC,c:1*00\$GPGGA,1*00`, talker: "GP", typ: "GGA"},
+		{in: "$GP", talker: "", typ: "GP"},
+		{in: "$,GPRMC,123519,A*00"},
+		{in: "$*00"},
+		{in: "$,"},
+		{in: "$"},
+		{in: `\s:SRC*00\$`},
+		{in: `\$,*00`},
+		{in: ""},
+		{in: "GPRMC,1"},
+	}
+	for _, tt := range tests {
+		talker, typ := addressOf(tt.in)
+		if talker != tt.talker || typ != tt.typ {
+			t.Errorf("addressOf(%q) = %q %q, want %q %q", tt.in, talker, typ, tt.talker, tt.typ)
+		}
+	}
+}
+
+// TestRecordMalformed runs malformed device input through CheckSum and Record, as the feeds do
+func TestRecordMalformed(t *testing.T) {
+	dev := &GpsDevice{FileIO: "test", Handler: HandlerFunc(func(Event) {})}
+	now := time.Now()
+	for _, in := range []string{"$,GPRMC,123519,A*00", "$*00", "$,*00", "$", "$*", `\s:SRC*00\$*00`, "!*00"} {
+		if dev.CheckSum(in) {
+			dev.Record(in, OutcomeOK, now)
+		}
+		dev.Record(in, OutcomeParse, now)
+	}
+	if len(dev.Stats()) == 0 {
+		t.Error("malformed sentences not counted")
+	}
+}
+
+// TestRecordBounded checks that corrupt and endless distinct input can not grow the counters
+func TestRecordBounded(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		o     Outcome
+		typ   func(i int) string
+		other uint64 // counted as other
+	}{
+		{name: "checksum", o: OutcomeChecksum, typ: func(i int) string { return "X" + strconv.Itoa(i) }, other: 1000},
+		{name: "parse", o: OutcomeParse, typ: func(i int) string { return "X" + strconv.Itoa(i) }, other: 1000},
+		{name: "malformed", o: OutcomeOK, typ: func(i int) string { return "x\x00" + strconv.Itoa(i) }, other: 1000},
+		{name: "cap", o: OutcomeUnsupported, typ: func(i int) string { return "X" + strconv.Itoa(i) }, other: 1000 - _maxStatKeys + 1},
+	}
+	for _, tt := range tests {
+		var s sentenceStats
+		s.record("GP", "RMC", OutcomeOK, now)
+		for i := 0; i < 1000; i++ {
+			s.record("GP", tt.typ(i), tt.o, now)
+		}
+		s.record("GP", "RMC", tt.o, now) // failures of a known talker+type keep their key
+		snap := s.snapshot()
+		if len(snap) > _maxStatKeys+1 {
+			t.Errorf("%s: %d counters, want at most %d", tt.name, len(snap), _maxStatKeys+1)
+		}
+		var other, rmc SentenceStats
+		for _, st := range snap {
+			switch (statKey{st.Talker, st.Type}) {
+			case _statOther:
+				other = st
+			case statKey{"GP", "RMC"}:
+				rmc = st
+			}
+		}
+		if other.Count != tt.other || rmc.Count != 2 {
+			t.Errorf("%s: other %d, RMC %d, want %d, 2", tt.name, other.Count, rmc.Count, tt.other)
+		}
+	}
+}
+
+// TestParseChecksumMode checks that Parse accepts exactly what CheckSum accepts, in both checksum modes
+func TestParseChecksumMode(t *testing.T) {
+	const rmc = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W"
+	tests := []struct {
+		in                 string
+		optional, required bool // valid without and with RequireChecksum
+	}{
+		{in: _rmc, optional: true, required: true},
+		{in: " " + _rmc + "\r\n", optional: true, required: true},
+		{in: rmc, optional: true},
+		{in: rmc + "\r\n", optional: true},
+		{in: rmc + "*00"},
+	}
+	for _, tt := range tests {
+		for require, want := range map[bool]bool{false: tt.optional, true: tt.required} {
+			dev := &GpsDevice{FileIO: "test", RequireChecksum: require, Handler: HandlerFunc(func(Event) {})}
+			_, err := dev.Parse(tt.in)
+			if valid := dev.CheckSum(tt.in); valid != want || (err == nil) != want {
+				t.Errorf("require %v: CheckSum(%q) = %v, Parse error %v, want valid %v", require, tt.in, valid, err, want)
+			}
+		}
+	}
+}
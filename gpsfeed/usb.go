@@ -0,0 +1,111 @@
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const _schemeUSB = "usb:"
+
+//
+// USB
+//
+
+// usbTTY is a tty device with its usb ids
+type usbTTY struct {
+	path    string // eg. /dev/ttyACM0
+	vendor  string // lower case hex, eg. 1546
+	product string // lower case hex, eg. 01a8
+	serial  string // usb serial number, if any
+}
+
+// isUSB ...
+func isUSB(name string) bool { return strings.HasPrefix(name, _schemeUSB) }
+
+// parseUSB parses usb:VID:PID[:SERIAL], empty fields match any
+func parseUSB(spec string) (USBMatch, error) {
+	if !isUSB(spec) {
+		return USBMatch{}, fmt.Errorf("not an usb device spec [%s]", spec)
+	}
+	t := strings.SplitN(strings.TrimPrefix(spec, _schemeUSB), ":", 3)
+	var m USBMatch
+	m.Vendor = strings.ToLower(t[0])
+	if len(t) > 1 {
+		m.Product = strings.ToLower(t[1])
+	}
+	if len(t) > 2 {
+		m.Serial = t[2]
+	}
+	for _, id := range []string{m.Vendor, m.Product} {
+		if len(id) > 4 || strings.Trim(id, "0123456789abcdef") != "" {
+			return USBMatch{}, fmt.Errorf("invalid usb id [%s] [%s]", id, spec)
+		}
+	}
+	if m == (USBMatch{}) {
+		return USBMatch{}, fmt.Errorf("empty usb device spec [%s]", spec)
+	}
+	return m, nil
+}
+
+// match ...
+func (m USBMatch) match(t usbTTY) bool {
+	return (m.Vendor == "" || m.Vendor == t.vendor) &&
+		(m.Product == "" || m.Product == t.product) &&
+		(m.Serial == "" || m.Serial == t.serial)
+}
+
+// resolve returns the first tty device matching m
+func (m USBMatch) resolve() (string, error) {
+	ttys, err := usbTTYs()
+	if err != nil {
+		return "", err
+	}
+	for _, t := range ttys {
+		if m.match(t) {
+			return t.path, nil
+		}
+	}
+	return "", fmt.Errorf("no usb tty device found [%s]", m.string())
+}
+
+// string ...
+func (m USBMatch) string() string {
+	return _schemeUSB + strings.TrimRight(m.Vendor+":"+m.Product+":"+m.Serial, ":")
+}
+
+// usbSource is a device file, resolved by usb ids on every open
+type usbSource struct {
+	fileSource
+	spec  string
+	match USBMatch
+	err   error // spec parse error
+	lock  sync.Mutex
+}
+
+// Open resolves the current device path and opens it
+func (s *usbSource) Open() error {
+	if s.err != nil {
+		return s.err
+	}
+	path, err := s.match.resolve()
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.path = path
+	s.lock.Unlock()
+	return s.fileSource.Open()
+}
+
+// Name ...
+func (s *usbSource) Name() string { return s.spec }
+
+// Path returns the device path of the last open
+func (s *usbSource) Path() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.path
+}
@@ -0,0 +1,53 @@
+//go:build linux
+
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	_sysClassTTY = "/sys/class/tty"
+	_usbDepth    = 4 // max levels from the tty device up to the usb device
+)
+
+// usbTTYs lists all usb tty devices via sysfs, sorted by name
+func usbTTYs() ([]usbTTY, error) {
+	entries, err := os.ReadDir(_sysClassTTY)
+	if err != nil {
+		return nil, err
+	}
+	var ttys []usbTTY
+	for _, e := range entries {
+		dir, err := filepath.EvalSymlinks(filepath.Join(_sysClassTTY, e.Name(), "device"))
+		if err != nil {
+			continue // virtual tty
+		}
+		for i := 0; i < _usbDepth; i++ {
+			if vendor := sysAttr(dir, "idVendor"); vendor != "" {
+				ttys = append(ttys, usbTTY{
+					path:    "/dev/" + e.Name(),
+					vendor:  strings.ToLower(vendor),
+					product: strings.ToLower(sysAttr(dir, "idProduct")),
+					serial:  sysAttr(dir, "serial"),
+				})
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+	return ttys, nil
+}
+
+// sysAttr reads a sysfs attribute, empty if missing
+func sysAttr(dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+// package gpsfeed ...
+package gpsfeed
+
+// import
+import "fmt"
+
+// usbTTYs ...
+func usbTTYs() ([]usbTTY, error) {
+	return nil, fmt.Errorf("usb device lookup is not supported on this platform")
+}
@@ -0,0 +1,84 @@
+// package nmea2k decodes NMEA 2000 CAN frames of common GNSS PGNs into nmeanano sentences
+package nmea2k
+
+// import
+import (
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// PGN
+//
+
+// supported parameter group numbers
+const (
+	PGNSystemTime        = 126992 // system time
+	PGNPositionRapid     = 129025 // position, rapid update
+	PGNCOGSOGRapid       = 129026 // cog & sog, rapid update
+	PGNGNSSPosition      = 129029 // gnss position data [fast packet]
+	PGNGNSSDOPs          = 129539 // gnss dops
+	PGNGNSSSatsInView    = 129540 // gnss sats in view [fast packet]
+	TalkerN2K            = "N2K"  // talker id of decoded sentences
+	_fastPacketMaxLength = 223    // max fast packet payload length
+)
+
+//
+// Frame
+//
+
+// Frame is a single raw CAN frame with an 29 bit extended identifier
+type Frame struct {
+	ID   uint32    // 29 bit can identifier
+	Data []byte    // frame payload [0-8 bytes]
+	Time time.Time // receive time stamp, if known
+}
+
+// PGN returns the parameter group number of the frame
+func (f Frame) PGN() uint32 { return pgn(f.ID) }
+
+// Source returns the source address of the frame
+func (f Frame) Source() uint8 { return uint8(f.ID & 0xFF) }
+
+// Priority returns the frame priority
+func (f Frame) Priority() uint8 { return uint8((f.ID >> 26) & 0x7) }
+
+// ParseCandump parses a single line of SocketCAN candump text output, eg. [can0 09F80102#A0B1C2D3E4F50617]
+// or [(1600000000.123456) can0 09F80102   [8]  A0 B1 C2 D3 E4 F5 06 17]
+func ParseCandump(line string) (Frame, error) { return parseCandump(line) }
+
+//
+// Decoder
+//
+
+// Decoder reassembles fast packets and merges the decoded PGNs into the nmeanano sentence types,
+// the zero value is ready to use
+type Decoder struct {
+	fast map[fastKey]*fastPacket
+	rmc  nmeanano.RMC
+	gga  nmeanano.GGA
+	gsa  nmeanano.GSA
+	gsv  nmeanano.GSV
+	vtg  nmeanano.VTG
+	mode int // last reported gnss method, -1 if unknown
+}
+
+// NewDecoder returns a new, empty Decoder
+func NewDecoder() *Decoder { return newDecoder() }
+
+// Decode feeds a frame into the Decoder and returns the updated sentences, if any
+func (d *Decoder) Decode(f Frame) ([]nmeanano.Sentence, error) { return d.decode(f) }
+
+//
+// Reader
+//
+
+// Reader reads raw CAN frames from an source
+type Reader interface {
+	ReadFrame() (Frame, error)
+	Close() error
+}
+
+// Open opens a CAN interface (eg. can0) or a candump text log file, "-" reads from stdin
+func Open(source string) (Reader, error) { return open(source) }
@@ -0,0 +1,332 @@
+// package nmea2k ...
+package nmea2k
+
+// import
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Frame
+//
+
+// pgn extracts the parameter group number from an 29 bit can identifier
+func pgn(id uint32) uint32 {
+	p := (id >> 8) & 0x1FFFF
+	if (p>>8)&0xFF < 240 { // PDU1, PS field is the destination address
+		p &= 0x1FF00
+	}
+	return p
+}
+
+// parseCandump ...
+func parseCandump(line string) (Frame, error) {
+	var f Frame
+	fields := strings.Fields(line)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "(") {
+		ts, err := strconv.ParseFloat(strings.Trim(fields[0], "()"), 64)
+		if err != nil {
+			return f, fmt.Errorf("nmea2k: invalid candump time stamp [%s]", fields[0])
+		}
+		sec, frac := math.Modf(ts)
+		f.Time = time.Unix(int64(sec), int64(frac*1e9))
+		fields = fields[1:]
+	}
+	if len(fields) < 2 {
+		return f, fmt.Errorf("nmea2k: invalid candump line [%s]", line)
+	}
+	fields = fields[1:] // interface name
+	var id, data string
+	if i := strings.Index(fields[0], "#"); i > -1 {
+		id, data = fields[0][:i], fields[0][i+1:]
+	} else {
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "[") {
+			return f, fmt.Errorf("nmea2k: invalid candump line [%s]", line)
+		}
+		id, data = fields[0], strings.Join(fields[2:], "")
+	}
+	v, err := strconv.ParseUint(id, 16, 32)
+	if err != nil || len(id) != 8 {
+		return f, fmt.Errorf("nmea2k: not an extended can identifier [%s]", id)
+	}
+	f.ID = uint32(v) & 0x1FFFFFFF
+	if f.Data, err = hex.DecodeString(data); err != nil || len(f.Data) > 8 {
+		return f, fmt.Errorf("nmea2k: invalid candump payload [%s]", data)
+	}
+	return f, nil
+}
+
+//
+// Fast Packet
+//
+
+// fastKey ...
+type fastKey struct {
+	pgn    uint32
+	source uint8
+}
+
+// fastPacket ...
+type fastPacket struct {
+	seq    byte
+	next   byte
+	length int
+	data   []byte
+}
+
+// isFastPacket ...
+func isFastPacket(p uint32) bool {
+	switch p {
+	case PGNGNSSPosition, PGNGNSSSatsInView:
+		return true
+	}
+	return false
+}
+
+// reassemble collects fast packet frames, returns the payload once complete
+func (d *Decoder) reassemble(f Frame) ([]byte, bool) {
+	if len(f.Data) < 2 {
+		return nil, false
+	}
+	key := fastKey{pgn: f.PGN(), source: f.Source()}
+	seq, counter := f.Data[0]>>5, f.Data[0]&0x1F
+	if counter == 0 {
+		p := &fastPacket{seq: seq, next: 1, length: int(f.Data[1])}
+		if p.length > _fastPacketMaxLength {
+			delete(d.fast, key)
+			return nil, false
+		}
+		p.data = append(p.data, f.Data[2:]...)
+		d.fast[key] = p
+	} else {
+		p, ok := d.fast[key]
+		if !ok || p.seq != seq || p.next != counter {
+			delete(d.fast, key) // lost frame, drop sequence
+			return nil, false
+		}
+		p.data = append(p.data, f.Data[1:]...)
+		p.next++
+	}
+	p := d.fast[key]
+	if len(p.data) < p.length {
+		return nil, false
+	}
+	delete(d.fast, key)
+	return p.data[:p.length], true
+}
+
+//
+// Decoder
+//
+
+// newDecoder ...
+func newDecoder() *Decoder {
+	d := &Decoder{}
+	d.init()
+	return d
+}
+
+// init prepares an empty Decoder, called lazily for the zero value
+func (d *Decoder) init() {
+	d.fast, d.mode = make(map[fastKey]*fastPacket), -1
+	d.rmc.BaseSentence = base(nmeanano.TypeRMC)
+	d.rmc.Validity = nmeanano.InvalidRMC
+	d.gga.BaseSentence = base(nmeanano.TypeGGA)
+	d.gga.FixQuality = nmeanano.Invalid
+	d.gsa.BaseSentence = base(nmeanano.TypeGSA)
+	d.gsv.BaseSentence = base(nmeanano.TypeGSV)
+	d.vtg.BaseSentence = base(nmeanano.TypeVTG)
+}
+
+// base ...
+func base(typ string) nmeanano.BaseSentence {
+	return nmeanano.BaseSentence{Talker: TalkerN2K, Type: typ}
+}
+
+// raw describes the source pgn of a decoded sentence
+func raw(f Frame, data []byte) string {
+	return fmt.Sprintf("[n2k] [pgn %d] [src %d] [%X]", f.PGN(), f.Source(), data)
+}
+
+// decode ...
+func (d *Decoder) decode(f Frame) ([]nmeanano.Sentence, error) {
+	if d.fast == nil {
+		d.init()
+	}
+	p := f.PGN()
+	data := f.Data
+	if isFastPacket(p) {
+		var ok bool
+		if data, ok = d.reassemble(f); !ok {
+			return nil, nil
+		}
+	}
+	r := raw(f, data)
+	switch p {
+	case PGNSystemTime:
+		if len(data) < 8 {
+			return nil, errShort(p, data)
+		}
+		d.setDateTime(binary.LittleEndian.Uint16(data[2:]), binary.LittleEndian.Uint32(data[4:]))
+		d.rmc.Raw = r
+		return []nmeanano.Sentence{d.rmc}, nil
+	case PGNPositionRapid:
+		if len(data) < 8 {
+			return nil, errShort(p, data)
+		}
+		lat, lon := int32(binary.LittleEndian.Uint32(data[0:])), int32(binary.LittleEndian.Uint32(data[4:]))
+		if lat == math.MaxInt32 || lon == math.MaxInt32 {
+			return nil, nil
+		}
+		d.rmc.Latitude, d.rmc.Longitude = float64(lat)*1e-7, float64(lon)*1e-7
+		if d.mode < 0 {
+			d.rmc.Validity = nmeanano.ValidRMC
+		}
+		d.rmc.Raw = r
+		return []nmeanano.Sentence{d.rmc}, nil
+	case PGNCOGSOGRapid:
+		if len(data) < 6 {
+			return nil, errShort(p, data)
+		}
+		cog, sog := binary.LittleEndian.Uint16(data[2:]), binary.LittleEndian.Uint16(data[4:])
+		if cog != math.MaxUint16 {
+			deg := float64(cog) * 1e-4 * 180 / math.Pi
+			d.rmc.Course = deg
+			if data[1]&0x03 == 1 {
+				d.vtg.MagneticTrack = deg
+			} else {
+				d.vtg.TrueTrack = deg
+			}
+		}
+		if sog != math.MaxUint16 {
+			ms := float64(sog) * 0.01
+			d.rmc.Speed = ms * 3600 / 1852
+			d.vtg.GroundSpeedKnots = d.rmc.Speed
+			d.vtg.GroundSpeedKPH = ms * 3.6
+		}
+		d.rmc.Raw, d.vtg.Raw = r, r
+		return []nmeanano.Sentence{d.rmc, d.vtg}, nil
+	case PGNGNSSPosition:
+		if len(data) < 43 {
+			return nil, errShort(p, data)
+		}
+		d.setDateTime(binary.LittleEndian.Uint16(data[1:]), binary.LittleEndian.Uint32(data[3:]))
+		lat, lon := int64(binary.LittleEndian.Uint64(data[7:])), int64(binary.LittleEndian.Uint64(data[15:]))
+		if lat != math.MaxInt64 && lon != math.MaxInt64 {
+			d.gga.Latitude, d.gga.Longitude = float64(lat)*1e-16, float64(lon)*1e-16
+			d.rmc.Latitude, d.rmc.Longitude = d.gga.Latitude, d.gga.Longitude
+		}
+		if alt := int64(binary.LittleEndian.Uint64(data[23:])); alt != math.MaxInt64 {
+			d.gga.Altitude = float64(alt) * 1e-6
+		}
+		d.mode = int(data[31] >> 4)
+		d.gga.FixQuality = nmeanano.Invalid
+		d.rmc.Validity = nmeanano.InvalidRMC
+		if d.mode <= 6 {
+			d.gga.FixQuality = strconv.Itoa(d.mode)
+		}
+		if d.mode > 0 && d.mode <= 5 {
+			d.rmc.Validity = nmeanano.ValidRMC
+		}
+		if data[33] != 0xFF {
+			d.gga.NumSatellites = int64(data[33])
+		}
+		if hdop := int16(binary.LittleEndian.Uint16(data[34:])); hdop != math.MaxInt16 {
+			d.gga.HDOP = float64(hdop) * 0.01
+		}
+		if sep := int32(binary.LittleEndian.Uint32(data[38:])); sep != math.MaxInt32 {
+			d.gga.Separation = float64(sep) * 0.01
+		}
+		d.gga.Time = d.rmc.Time
+		d.gga.Raw, d.rmc.Raw = r, r
+		return []nmeanano.Sentence{d.gga, d.rmc}, nil
+	case PGNGNSSDOPs:
+		if len(data) < 8 {
+			return nil, errShort(p, data)
+		}
+		d.gsa.Mode = nmeanano.Manual
+		if data[1]&0x07 == 3 {
+			d.gsa.Mode = nmeanano.Auto
+		}
+		switch (data[1] >> 3) & 0x07 {
+		case 1:
+			d.gsa.FixType = nmeanano.Fix2D
+		case 2:
+			d.gsa.FixType = nmeanano.Fix3D
+		default:
+			d.gsa.FixType = nmeanano.FixNone
+		}
+		hdop, vdop := int16(binary.LittleEndian.Uint16(data[2:])), int16(binary.LittleEndian.Uint16(data[4:]))
+		if hdop != math.MaxInt16 {
+			d.gsa.HDOP = float64(hdop) * 0.01
+		}
+		if vdop != math.MaxInt16 {
+			d.gsa.VDOP = float64(vdop) * 0.01
+		}
+		d.gsa.PDOP = math.Round(math.Hypot(d.gsa.HDOP, d.gsa.VDOP)*100) / 100
+		d.gsa.Raw = r
+		return []nmeanano.Sentence{d.gsa}, nil
+	case PGNGNSSSatsInView:
+		if len(data) < 3 {
+			return nil, errShort(p, data)
+		}
+		n := int(data[2])
+		if n == 0xFF || len(data) < 3+n*12 {
+			return nil, errShort(p, data)
+		}
+		d.gsv.TotalMessages, d.gsv.MessageNumber, d.gsv.NumberSVsInView = 1, 1, int64(n)
+		d.gsv.Info = make([]nmeanano.GSVInfo, 0, n)
+		d.gsa.SV = make([]string, 0, n) // returned gsa keep their own slice
+		for i := 0; i < n; i++ {
+			sat := data[3+i*12:]
+			info := nmeanano.GSVInfo{SVPRNNumber: int64(sat[0])}
+			if el := int16(binary.LittleEndian.Uint16(sat[1:])); el != math.MaxInt16 {
+				info.Elevation = int64(math.Round(float64(el) * 1e-4 * 180 / math.Pi))
+			}
+			if az := binary.LittleEndian.Uint16(sat[3:]); az != math.MaxUint16 {
+				info.Azimuth = int64(math.Round(float64(az) * 1e-4 * 180 / math.Pi))
+			}
+			if snr := binary.LittleEndian.Uint16(sat[5:]); snr != math.MaxUint16 {
+				info.SNR = int64(math.Round(float64(snr) * 0.01))
+			}
+			if status := sat[11] & 0x0F; status == 2 || status == 5 { // used, used + diff [3, 4: not used, diff avail]
+				d.gsa.SV = append(d.gsa.SV, strconv.FormatInt(info.SVPRNNumber, 10))
+			}
+			d.gsv.Info = append(d.gsv.Info, info)
+		}
+		d.gsv.Raw = r
+		return []nmeanano.Sentence{d.gsv}, nil
+	}
+	return nil, nil
+}
+
+// setDateTime updates the rmc date and time from days since epoch and 1/10000 seconds since midnight
+func (d *Decoder) setDateTime(days uint16, ticks uint32) {
+	if days != math.MaxUint16 {
+		t := time.Unix(int64(days)*86400, 0).UTC()
+		d.rmc.Date = nmeanano.Date{Valid: true, DD: t.Day(), MM: int(t.Month()), YY: t.Year() % 100}
+	}
+	if ticks != math.MaxUint32 {
+		ms := int(ticks / 10)
+		d.rmc.Time = nmeanano.Time{
+			Valid:       true,
+			Hour:        ms / 3600000,
+			Minute:      ms / 60000 % 60,
+			Second:      ms / 1000 % 60,
+			Millisecond: ms % 1000,
+		}
+	}
+}
+
+// errShort ...
+func errShort(p uint32, data []byte) error {
+	return fmt.Errorf("nmea2k: pgn %d payload too short [%d bytes]", p, len(data))
+}
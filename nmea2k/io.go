@@ -0,0 +1,55 @@
+// package nmea2k ...
+package nmea2k
+
+// import
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+//
+// FILE IO
+//
+
+// open ...
+func open(source string) (Reader, error) {
+	if source == "-" {
+		return newCandumpReader(os.Stdin), nil
+	}
+	if fi, err := os.Stat(source); err == nil && fi.Mode().IsRegular() {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		return newCandumpReader(f), nil
+	}
+	return openCAN(source)
+}
+
+// candumpReader reads frames from candump text output
+type candumpReader struct {
+	rc   io.ReadCloser
+	feed *bufio.Scanner
+}
+
+// newCandumpReader ...
+func newCandumpReader(rc io.ReadCloser) *candumpReader {
+	return &candumpReader{rc: rc, feed: bufio.NewScanner(rc)}
+}
+
+// ReadFrame returns the next frame, invalid lines are skipped
+func (r *candumpReader) ReadFrame() (Frame, error) {
+	for r.feed.Scan() {
+		if f, err := parseCandump(r.feed.Text()); err == nil {
+			return f, nil
+		}
+	}
+	if err := r.feed.Err(); err != nil {
+		return Frame{}, err
+	}
+	return Frame{}, io.EOF
+}
+
+// Close ...
+func (r *candumpReader) Close() error { return r.rc.Close() }
@@ -0,0 +1,80 @@
+// package nmea2k ...
+package nmea2k
+
+// import
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+//
+// SOCKETCAN
+//
+
+const (
+	_afCAN       = 29         // AF_CAN
+	_canRaw      = 1          // CAN_RAW
+	_canEFFFlag  = 0x80000000 // extended frame format
+	_canFrameLen = 16         // sizeof(struct can_frame)
+)
+
+// sockaddrCAN mirrors struct sockaddr_can
+type sockaddrCAN struct {
+	family  uint16
+	_       uint16
+	ifindex int32
+	_       [16]byte
+}
+
+// canReader reads frames from a raw SocketCAN socket
+type canReader struct {
+	fd int
+}
+
+// openCAN binds a raw socket to the named can interface
+func openCAN(ifname string) (Reader, error) {
+	ifi, err := net.InterfaceByName(ifname)
+	if err != nil {
+		return nil, fmt.Errorf("nmea2k: unknown candump file or can interface [%s]", ifname)
+	}
+	fd, err := syscall.Socket(_afCAN, syscall.SOCK_RAW, _canRaw)
+	if err != nil {
+		return nil, fmt.Errorf("nmea2k: can socket [%s] [%w]", ifname, err)
+	}
+	sa := sockaddrCAN{family: _afCAN, ifindex: int32(ifi.Index)}
+	if _, _, errno := syscall.Syscall(syscall.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa)); errno != 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("nmea2k: can bind [%s] [%w]", ifname, errno)
+	}
+	return &canReader{fd: fd}, nil
+}
+
+// ReadFrame returns the next extended frame, standard frames are skipped
+func (r *canReader) ReadFrame() (Frame, error) {
+	buf := make([]byte, _canFrameLen)
+	for {
+		n, err := syscall.Read(r.fd, buf)
+		if err != nil {
+			return Frame{}, err
+		}
+		if n < _canFrameLen {
+			continue
+		}
+		id := binary.NativeEndian.Uint32(buf[0:])
+		if id&_canEFFFlag == 0 {
+			continue
+		}
+		l := int(buf[4])
+		if l > 8 {
+			l = 8
+		}
+		return Frame{ID: id & 0x1FFFFFFF, Data: append([]byte(nil), buf[8:8+l]...), Time: time.Now()}, nil
+	}
+}
+
+// Close ...
+func (r *canReader) Close() error { return syscall.Close(r.fd) }
@@ -0,0 +1,12 @@
+//go:build !linux
+
+// package nmea2k ...
+package nmea2k
+
+// import
+import "fmt"
+
+// openCAN ...
+func openCAN(ifname string) (Reader, error) {
+	return nil, fmt.Errorf("nmea2k: can interfaces are only supported on linux [%s]", ifname)
+}
@@ -0,0 +1,98 @@
+package nmea2k
+
+import (
+	"slices"
+	"testing"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+// fastFrames splits a payload into fast packet frames of sequence 0
+func fastFrames(pgn uint32, src uint8, payload []byte) []Frame {
+	id := 6<<26 | pgn<<8 | uint32(src)
+	frames := []Frame{{ID: id, Data: append([]byte{0x00, byte(len(payload))}, payload[:min(6, len(payload))]...)}}
+	for i, counter := 6, byte(1); i < len(payload); i, counter = i+7, counter+1 {
+		frames = append(frames, Frame{ID: id, Data: append([]byte{counter}, payload[i:min(i+7, len(payload))]...)})
+	}
+	return frames
+}
+
+// satsInView returns a pgn 129540 payload with one satellite per status, prn = status + 1
+func satsInView(statuses ...byte) []byte {
+	payload := []byte{0x01, 0xFF, byte(len(statuses))}
+	for _, status := range statuses {
+		sat := make([]byte, 12)
+		sat[0] = status + 1
+		sat[11] = 0xF0 | status
+		payload = append(payload, sat...)
+	}
+	return payload
+}
+
+// TestSatsInViewUsed ...
+func TestSatsInViewUsed(t *testing.T) {
+	var d Decoder // zero value
+	var sentences []nmeanano.Sentence
+	for _, f := range fastFrames(PGNGNSSSatsInView, 7, satsInView(0, 1, 2, 3, 4, 5)) {
+		s, err := d.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sentences = append(sentences, s...)
+	}
+	if len(sentences) != 1 {
+		t.Fatalf("sentences = %d, want 1", len(sentences))
+	}
+	gsv, ok := sentences[0].(nmeanano.GSV)
+	if !ok || gsv.NumberSVsInView != 6 || len(gsv.Info) != 6 {
+		t.Fatalf("gsv = %+v", sentences[0])
+	}
+	if want := []string{"3", "6"}; !slices.Equal(d.gsa.SV, want) { // status 2 and 5
+		t.Errorf("used = %v, want %v", d.gsa.SV, want)
+	}
+}
+
+// TestZeroDecoder ...
+func TestZeroDecoder(t *testing.T) {
+	var d Decoder
+	frames := fastFrames(PGNGNSSPosition, 3, make([]byte, 43))
+	if _, err := d.Decode(frames[0]); err != nil {
+		t.Fatal(err)
+	}
+	if d.mode != -1 || d.rmc.Type != nmeanano.TypeRMC {
+		t.Errorf("zero value decoder not initialised: mode %d, rmc %q", d.mode, d.rmc.Type)
+	}
+}
+
+// decodeAll decodes the frames and returns all sentences
+func decodeAll(t *testing.T, d *Decoder, frames []Frame) []nmeanano.Sentence {
+	t.Helper()
+	var sentences []nmeanano.Sentence
+	for _, f := range frames {
+		s, err := d.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sentences = append(sentences, s...)
+	}
+	return sentences
+}
+
+// TestGSAOwnSlice checks that a returned gsa is not changed by later sats in view
+func TestGSAOwnSlice(t *testing.T) {
+	var d Decoder
+	dops := []Frame{{ID: 2<<26 | PGNGNSSDOPs<<8 | 7, Data: []byte{0x01, 0x13, 100, 0, 150, 0, 0xFF, 0x7F}}}
+	decodeAll(t, &d, fastFrames(PGNGNSSSatsInView, 7, satsInView(2, 5)))
+	first := decodeAll(t, &d, dops)
+	decodeAll(t, &d, fastFrames(PGNGNSSSatsInView, 7, satsInView(5)))
+	second := decodeAll(t, &d, dops)
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("sentences = %d %d, want 1 1", len(first), len(second))
+	}
+	if gsa := first[0].(nmeanano.GSA); !slices.Equal(gsa.SV, []string{"3", "6"}) {
+		t.Errorf("first gsa used = %v, want [3 6]", gsa.SV)
+	}
+	if gsa := second[0].(nmeanano.GSA); !slices.Equal(gsa.SV, []string{"6"}) {
+		t.Errorf("second gsa used = %v, want [6]", gsa.SV)
+	}
+}
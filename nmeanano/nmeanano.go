@@ -27,6 +27,7 @@
 package nmeanano
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -487,11 +488,6 @@ const (
 	ChecksumSep               = "*"
 )
 
-var (
-	customParsersMu = &sync.Mutex{}
-	customParsers   = map[string]ParserFunc{}
-)
-
 type (
 	ParserFunc func(BaseSentence) (Sentence, error)
 	Sentence   interface {
@@ -524,46 +520,25 @@ func (s BaseSentence) TalkerID() string {
 	return s.Talker
 }
 func (s BaseSentence) String() string { return s.Raw }
-func parseSentence(raw string) (BaseSentence, error) {
-	raw = strings.TrimSpace(raw)
-	tagBlockParts := strings.SplitN(raw, `\`, 3)
-	var (
-		tagBlock TagBlock
-		err      error
-	)
-	if len(tagBlockParts) == 3 {
-		tags := tagBlockParts[1]
-		raw = tagBlockParts[2]
-		tagBlock, err = parseTagBlock(tags)
-		if err != nil {
+func parseSentence(raw string, mode ChecksumMode) (BaseSentence, error) {
+	tagBlockRaw, sentence, fieldsRaw, checksumRaw, err := splitChecksum(raw, mode)
+	if err != nil {
+		return BaseSentence{}, err
+	}
+	var tagBlock TagBlock
+	if tagBlockRaw != "" {
+		if tagBlock, err = parseTagBlock(tagBlockRaw[1 : len(tagBlockRaw)-1]); err != nil {
 			return BaseSentence{}, err
 		}
 	}
-	startIndex := strings.IndexAny(raw, SentenceStart+SentenceStartEncapsulated)
-	if startIndex != 0 {
-		return BaseSentence{}, fmt.Errorf("nmea: sentence does not start with a '$' or '!'")
-	}
-	sumSepIndex := strings.Index(raw, ChecksumSep)
-	if sumSepIndex == -1 {
-		return BaseSentence{}, fmt.Errorf("nmea: sentence does not contain checksum separator")
-	}
-	var (
-		fieldsRaw   = raw[startIndex+1 : sumSepIndex]
-		fields      = strings.Split(fieldsRaw, FieldSep)
-		checksumRaw = strings.ToUpper(raw[sumSepIndex+1:])
-		checksum    = Checksum(fieldsRaw)
-	)
-	if checksum != checksumRaw {
-		return BaseSentence{}, fmt.Errorf(
-			"nmea: sentence checksum mismatch [%s != %s]", checksum, checksumRaw)
-	}
+	fields := strings.Split(fieldsRaw, FieldSep)
 	talker, typ := parsePrefix(fields[0])
 	return BaseSentence{
 		Talker:   talker,
 		Type:     typ,
 		Fields:   fields[1:],
 		Checksum: checksumRaw,
-		Raw:      raw,
+		Raw:      sentence,
 		TagBlock: tagBlock,
 	}, nil
 }
@@ -581,38 +556,172 @@ func parsePrefix(s string) (string, string) {
 	return s[:2], s[2:]
 }
 
+// Checksum returns the xor checksum of s as two digit upper case hex
 func Checksum(s string) string {
 	var checksum uint8
 	for i := 0; i < len(s); i++ {
 		checksum ^= s[i]
 	}
-	return fmt.Sprintf("%02X", checksum)
+	return string([]byte{_hex[checksum>>4], _hex[checksum&0xF]})
 }
 
-func MustRegisterParser(sentenceType string, parser ParserFunc) {
-	if err := RegisterParser(sentenceType, parser); err != nil {
-		panic(err)
+const _hex = "0123456789ABCDEF"
+
+// ChecksumMode controls the validation of sentences without checksum
+type ChecksumMode int
+
+// checksum modes
+const (
+	ChecksumRequired ChecksumMode = iota // a missing checksum is an error
+	ChecksumOptional                     // a missing checksum is valid
+)
+
+// ValidateChecksum verifies the checksum of a raw sentence, with or without tag block. It tolerates
+// lower case hex and surrounding whitespace [eg. line ends] and never panics on malformed input.
+func ValidateChecksum(raw string, mode ChecksumMode) error {
+	_, _, _, _, err := splitChecksum(raw, mode)
+	return err
+}
+
+// ChecksumValid reports if ValidateChecksum accepts the raw sentence
+func ChecksumValid(raw string, mode ChecksumMode) bool {
+	_, _, _, _, err := splitChecksum(raw, mode)
+	return err == nil
+}
+
+// splitChecksum trims surrounding whitespace, validates raw and returns the tag block incl. backslashes,
+// the sentence, its checksummed part without start char and the upper case checksum. Parse and the
+// checksum validation share it, so both agree on every input.
+func splitChecksum(raw string, mode ChecksumMode) (tagBlockRaw, sentence, fieldsRaw, checksumRaw string, err error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, `\`) {
+		end := strings.IndexByte(raw[1:], '\\')
+		if end == -1 {
+			return "", "", "", "", fmt.Errorf("nmea: tagblock is not terminated")
+		}
+		tagBlockRaw, raw = raw[:end+2], raw[end+2:]
 	}
+	if raw == "" || (raw[:1] != SentenceStart && raw[:1] != SentenceStartEncapsulated) {
+		return "", "", "", "", fmt.Errorf("nmea: sentence does not start with a '$' or '!'")
+	}
+	sumSepIndex := strings.Index(raw, ChecksumSep)
+	if sumSepIndex == -1 {
+		if mode == ChecksumOptional {
+			return tagBlockRaw, raw, raw[1:], "", nil
+		}
+		return "", "", "", "", fmt.Errorf("nmea: sentence does not contain checksum separator")
+	}
+	fieldsRaw, checksumRaw = raw[1:sumSepIndex], strings.ToUpper(raw[sumSepIndex+1:])
+	if checksum := Checksum(fieldsRaw); checksum != checksumRaw {
+		return "", "", "", "", fmt.Errorf("nmea: sentence checksum mismatch [%s != %s]", checksum, checksumRaw)
+	}
+	return tagBlockRaw, raw, fieldsRaw, checksumRaw, nil
 }
 
-func RegisterParser(sentenceType string, parser ParserFunc) error {
-	customParsersMu.Lock()
-	defer customParsersMu.Unlock()
-	if _, ok := customParsers[sentenceType]; ok {
-		return fmt.Errorf("nmea: parser for sentence type '%q' already exists", sentenceType)
+// Registry holds custom sentence parsers, keyed either by full prefix (talker+type, eg. GPRMC)
+// or by sentence type only (eg. RMC). The full prefix takes precedence on lookup. A Registry
+// is safe for concurrent use, the zero value is an empty, ready to use Registry.
+type Registry struct {
+	mu      sync.RWMutex
+	parsers map[string]ParserFunc
+}
+
+// DefaultRegistry is the Registry used by Parse, RegisterParser and UnregisterParser
+var DefaultRegistry = NewRegistry()
+
+// ErrUnsupported is wrapped by Parse errors for sentences without parser
+var ErrUnsupported = errors.New("not supported")
+
+// NewRegistry returns a new, empty Registry
+func NewRegistry() *Registry {
+	return &Registry{parsers: map[string]ParserFunc{}}
+}
+
+// Register adds a parser for a sentence prefix or type, fails if the key is already taken
+func (r *Registry) Register(key string, parser ParserFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.parsers[key]; ok {
+		return fmt.Errorf("nmea: parser for sentence type '%q' already exists", key)
+	}
+	if r.parsers == nil {
+		r.parsers = map[string]ParserFunc{}
 	}
-	customParsers[sentenceType] = parser
+	r.parsers[key] = parser
 	return nil
 }
 
-func Parse(raw string) (Sentence, error) {
-	s, err := parseSentence(raw)
+// Override adds or replaces a parser for a sentence prefix or type, reports if one got replaced
+func (r *Registry) Override(key string, parser ParserFunc) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.parsers[key]
+	if r.parsers == nil {
+		r.parsers = map[string]ParserFunc{}
+	}
+	r.parsers[key] = parser
+	return ok
+}
+
+// Unregister removes the parser for a sentence prefix or type, reports if one was registered
+func (r *Registry) Unregister(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.parsers[key]
+	delete(r.parsers, key)
+	return ok
+}
+
+// Lookup returns the custom parser for a sentence, full prefix first, sentence type second
+func (r *Registry) Lookup(s BaseSentence) (ParserFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if parser, ok := r.parsers[s.Prefix()]; ok {
+		return parser, true
+	}
+	parser, ok := r.parsers[s.Type]
+	return parser, ok
+}
+
+// Parse parses a raw sentence, custom parsers of this Registry take precedence over the builtin ones
+func (r *Registry) Parse(raw string) (Sentence, error) { return r.ParseMode(raw, ChecksumRequired) }
+
+// ParseMode parses a raw sentence like Parse, mode controls sentences without checksum
+func (r *Registry) ParseMode(raw string, mode ChecksumMode) (Sentence, error) {
+	s, err := parseSentence(raw, mode)
 	if err != nil {
 		return nil, err
 	}
-	if parser, ok := customParsers[s.Type]; ok {
+	if parser, ok := r.Lookup(s); ok {
 		return parser(s)
 	}
+	return parseBuiltin(s)
+}
+
+func MustRegisterParser(sentenceType string, parser ParserFunc) {
+	if err := RegisterParser(sentenceType, parser); err != nil {
+		panic(err)
+	}
+}
+
+func RegisterParser(sentenceType string, parser ParserFunc) error {
+	return DefaultRegistry.Register(sentenceType, parser)
+}
+
+func UnregisterParser(sentenceType string) bool {
+	return DefaultRegistry.Unregister(sentenceType)
+}
+
+func Parse(raw string) (Sentence, error) {
+	return DefaultRegistry.Parse(raw)
+}
+
+// ParseMode parses a raw sentence with the DefaultRegistry, mode controls sentences without checksum
+func ParseMode(raw string, mode ChecksumMode) (Sentence, error) {
+	return DefaultRegistry.ParseMode(raw, mode)
+}
+
+func parseBuiltin(s BaseSentence) (Sentence, error) {
 	if strings.HasPrefix(s.Raw, SentenceStart) {
 		switch s.Type {
 		case TypeRMC:
@@ -629,7 +738,7 @@ func Parse(raw string) (Sentence, error) {
 			return newGNS(s)
 		}
 	}
-	return nil, fmt.Errorf("nmea: sentence prefix '%s' not supported", s.Prefix())
+	return nil, fmt.Errorf("nmea: sentence prefix '%s' %w", s.Prefix(), ErrUnsupported)
 }
 
 type TagBlock struct {
@@ -702,6 +811,208 @@ func parseTagBlock(tags string) (TagBlock, error) {
 	return tagBlock, nil
 }
 
+// String formats the TagBlock as checksummed NMEA 4.x tag block, eg. [\c:1577836800,s:r01*4E\]
+// Empty fields are omitted, an empty TagBlock formats as empty string.
+func (t TagBlock) String() string {
+	var fields []string
+	if t.Time != 0 {
+		fields = append(fields, "c:"+strconv.FormatInt(t.Time, 10))
+	}
+	if t.Destination != "" {
+		fields = append(fields, "d:"+t.Destination)
+	}
+	if t.Grouping != "" {
+		fields = append(fields, "g:"+t.Grouping)
+	}
+	if t.LineCount != 0 {
+		fields = append(fields, "n:"+strconv.FormatInt(t.LineCount, 10))
+	}
+	if t.RelativeTime != 0 {
+		fields = append(fields, "r:"+strconv.FormatInt(t.RelativeTime, 10))
+	}
+	if t.Source != "" {
+		fields = append(fields, "s:"+t.Source)
+	}
+	if t.Text != "" {
+		fields = append(fields, "t:"+t.Text)
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	raw := strings.Join(fields, ",")
+	return `\` + raw + ChecksumSep + Checksum(raw) + `\`
+}
+
+// maxGroupSize caps the sentences of a tag block group, larger sizes are rejected as malformed
+const maxGroupSize = 99
+
+// Group decodes the grouping field [g:<index>-<size>-<id>] of the TagBlock, size is capped at 99
+func (t TagBlock) Group() (index, size, id int64, err error) {
+	parts := strings.Split(t.Grouping, "-")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("nmea: tagblock grouping is malformed [%s]", t.Grouping)
+	}
+	if index, err = parseInt64(parts[0]); err != nil {
+		return 0, 0, 0, err
+	}
+	if size, err = parseInt64(parts[1]); err != nil {
+		return 0, 0, 0, err
+	}
+	if id, err = parseInt64(parts[2]); err != nil {
+		return 0, 0, 0, err
+	}
+	if size < 1 || size > maxGroupSize || index < 1 || index > size {
+		return 0, 0, 0, fmt.Errorf("nmea: tagblock grouping is out of range [%s]", t.Grouping)
+	}
+	return index, size, id, nil
+}
+
+// FormatTagBlock prefixes a raw sentence with the checksummed tag block
+func FormatTagBlock(t TagBlock, sentence string) string {
+	return t.String() + sentence
+}
+
+// Group is a reassembled NMEA 4.x sentence group, Sentences are ordered by group index
+type Group struct {
+	ID        int64
+	Source    string
+	Sentences []BaseSentence
+}
+
+// maxPendingGroups is the default cap of incomplete groups of a GroupAssembler
+const maxPendingGroups = 256
+
+// GroupAssembler reassembles tag block grouped sentences [g:1-3-42] into one logical Group.
+// Groups are keyed by source [s:] and group id, a part without source joins the most recent
+// incomplete group with its id. Incomplete groups older than MaxAge are dropped, beyond
+// MaxPending the oldest one is.
+// A GroupAssembler is safe for concurrent use, the zero value is ready to use.
+type GroupAssembler struct {
+	MaxAge     time.Duration // drop incomplete groups after, 0 keeps them until Reset
+	MaxPending int           // max incomplete groups, 0 means 256
+	mu         sync.Mutex
+	pending    map[groupKey]*pendingGroup
+}
+
+// groupKey identifies an incomplete group, group ids are only unique per source
+type groupKey struct {
+	source string
+	id     int64
+}
+
+type pendingGroup struct {
+	seen  time.Time
+	count int
+	group Group
+}
+
+// Add feeds a sentence into the assembler. Sentences without grouping are returned as single
+// sentence Group right away, grouped ones once the last missing part has been added.
+func (a *GroupAssembler) Add(s BaseSentence) (Group, bool, error) {
+	if s.TagBlock.Grouping == "" {
+		return Group{Source: s.TagBlock.Source, Sentences: []BaseSentence{s}}, true, nil
+	}
+	index, size, id, err := s.TagBlock.Group()
+	if err != nil {
+		return Group{}, false, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	a.expire(now)
+	if a.pending == nil {
+		a.pending = map[groupKey]*pendingGroup{}
+	}
+	key, p := a.find(s.TagBlock.Source, id)
+	if p == nil || int64(len(p.group.Sentences)) != size {
+		p = &pendingGroup{seen: now, group: Group{ID: id, Source: key.source, Sentences: make([]BaseSentence, size)}}
+		a.pending[key] = p
+		a.limit(key)
+	}
+	if p.group.Sentences[index-1].Raw == "" {
+		p.count++
+	}
+	p.group.Sentences[index-1] = s
+	p.seen = now
+	if int64(p.count) < size {
+		return Group{}, false, nil
+	}
+	delete(a.pending, key)
+	return p.group, true, nil
+}
+
+// find returns the key and the incomplete group of a part, nil if there is none. A part without
+// source joins the most recent group with its id, a source on a later part claims a group
+// started without one. Caller holds the lock.
+func (a *GroupAssembler) find(source string, id int64) (groupKey, *pendingGroup) {
+	key, anon := groupKey{source: source, id: id}, groupKey{id: id}
+	if p, ok := a.pending[key]; ok {
+		return key, p
+	}
+	if source != "" {
+		p, ok := a.pending[anon]
+		if !ok {
+			return key, nil
+		}
+		delete(a.pending, anon)
+		p.group.Source = source
+		a.pending[key] = p
+		return key, p
+	}
+	var latest *pendingGroup
+	for k, p := range a.pending {
+		if k.id == id && (latest == nil || p.seen.After(latest.seen)) {
+			key, latest = k, p
+		}
+	}
+	return key, latest
+}
+
+// limit drops the oldest incomplete groups beyond MaxPending but the added one, caller holds the lock
+func (a *GroupAssembler) limit(added groupKey) {
+	max := a.MaxPending
+	if max <= 0 {
+		max = maxPendingGroups
+	}
+	for len(a.pending) > max {
+		var key groupKey
+		var oldest *pendingGroup
+		for k, p := range a.pending {
+			if k != added && (oldest == nil || p.seen.Before(oldest.seen)) {
+				key, oldest = k, p
+			}
+		}
+		delete(a.pending, key)
+	}
+}
+
+// Pending returns the number of incomplete groups
+func (a *GroupAssembler) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire(time.Now())
+	return len(a.pending)
+}
+
+// Reset drops all incomplete groups
+func (a *GroupAssembler) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending = nil
+}
+
+// expire drops incomplete groups older than MaxAge, caller holds the lock
+func (a *GroupAssembler) expire(now time.Time) {
+	if a.MaxAge <= 0 {
+		return
+	}
+	for key, p := range a.pending {
+		if now.Sub(p.seen) > a.MaxAge {
+			delete(a.pending, key)
+		}
+	}
+}
+
 const (
 	Degrees = '\u00B0'
 	Minutes = '\''
@@ -749,6 +1060,8 @@ func ParseGPS(s string) (float64, error) {
 	}
 }
 
+// FormatGPS returns the unsigned nmea [d]ddmm.mmmm magnitude of l, without hemisphere. Use
+// FormatLatGPS or FormatLonGPS for a value that round-trips through ParseGPS and ParseLatLong.
 func FormatGPS(l float64) string {
 	padding := ""
 	degrees := math.Floor(math.Abs(l))
@@ -759,6 +1072,23 @@ func FormatGPS(l float64) string {
 	return fmt.Sprintf("%d%s%.4f", int(degrees), padding, fraction)
 }
 
+// FormatLatGPS returns the latitude as nmea ddmm.mmmm with hemisphere, eg. [5333.8542 N]
+func FormatLatGPS(l float64) string { return formatGPSDir(l, 2, North, South) }
+
+// FormatLonGPS returns the longitude as nmea dddmm.mmmm with hemisphere, eg. [00957.4411 E]
+func FormatLonGPS(l float64) string { return formatGPSDir(l, 3, East, West) }
+
+// formatGPSDir ...
+func formatGPSDir(l float64, width int, pos, neg string) string {
+	dir := pos
+	if l < 0 {
+		dir = neg
+	}
+	m := math.Round(math.Abs(l)*60*1e4) / 1e4 // round on the printed precision, avoids 60.0000 minutes
+	degrees := math.Floor(m / 60)
+	return fmt.Sprintf("%0*d%07.4f %s", width, int(degrees), m-degrees*60, dir)
+}
+
 func ParseDecimal(s string) (float64, error) {
 	l, err := strconv.ParseFloat(s, 64)
 	if err != nil || s[0] != '-' && len(strings.Split(s, ".")[0]) > 3 {
@@ -767,16 +1097,160 @@ func ParseDecimal(s string) (float64, error) {
 	return l, nil
 }
 
-func ParseDMS(_ string) (float64, error) {
-	return 0, fmt.Errorf("unsuported")
+// ParseDMS parses degrees, minutes, seconds coordinates, eg. [53° 33' 51.25" N], [-9d57'26.5"]
+// The degree marker is mandatory, minutes and seconds are optional, the hemisphere can be
+// given as leading sign or as leading/trailing N/S/E/W letter.
+func ParseDMS(s string) (float64, error) {
+	var (
+		sign                   = 1.0
+		dir                    string
+		num                    strings.Builder
+		deg, min, sec          float64
+		hasDeg, hasMin, hasSec bool
+		err                    error
+		in                     = strings.TrimSpace(s)
+	)
+	if in == "" {
+		return 0, fmt.Errorf("parse error (not dms coordinate)")
+	}
+	switch in[0] {
+	case '-':
+		sign, in = -1, in[1:]
+	case '+':
+		in = in[1:]
+	}
+	if d := hemisphere(in); d != "" {
+		dir = d
+		in = strings.TrimSpace(strings.Trim(in, North+South+East+West))
+	}
+	for _, r := range in {
+		switch {
+		case r >= '0' && r <= '9' || r == Point:
+			num.WriteRune(r)
+		case r == ' ':
+		case r == Degrees || r == 'd':
+			if hasDeg || num.Len() == 0 {
+				return 0, fmt.Errorf("invalid dms degrees [%s]", s)
+			}
+			if deg, err = strconv.ParseFloat(num.String(), 64); err != nil {
+				return 0, fmt.Errorf("parse error: %s", err.Error())
+			}
+			hasDeg = true
+			num.Reset()
+		case r == Minutes || r == '\u2032':
+			if !hasDeg || hasMin || num.Len() == 0 {
+				return 0, fmt.Errorf("invalid dms minutes [%s]", s)
+			}
+			if min, err = strconv.ParseFloat(num.String(), 64); err != nil {
+				return 0, fmt.Errorf("parse error: %s", err.Error())
+			}
+			hasMin = true
+			num.Reset()
+		case r == Seconds || r == '\u2033':
+			if !hasMin || hasSec || num.Len() == 0 {
+				return 0, fmt.Errorf("invalid dms seconds [%s]", s)
+			}
+			if sec, err = strconv.ParseFloat(num.String(), 64); err != nil {
+				return 0, fmt.Errorf("parse error: %s", err.Error())
+			}
+			hasSec = true
+			num.Reset()
+		default:
+			return 0, fmt.Errorf("invalid dms character [%c] in [%s]", r, s)
+		}
+	}
+	if !hasDeg || num.Len() > 0 {
+		return 0, fmt.Errorf("parse error (not dms coordinate)")
+	}
+	if min >= 60 || sec >= 60 {
+		return 0, fmt.Errorf("invalid dms minutes or seconds out of range [%s]", s)
+	}
+	value := deg + min/60 + sec/3600
+	switch dir {
+	case South, West:
+		if sign < 0 {
+			return 0, fmt.Errorf("invalid dms sign and direction [%s]", s)
+		}
+		sign = -1
+	}
+	return sign * value, nil
 }
 
+// ParseLatLongPair parses an human typed coordinate pair in any format understood by
+// ParseLatLong, eg. [53.5642, 9.9573], [53°33'51.3"N 9°57'26.5"E], [5333.8542 N 00957.4411 E]
+func ParseLatLongPair(s string) (lat, lon float64, err error) {
+	var a, b string
+	if i := strings.IndexAny(s, ",;"); i > -1 {
+		a, b = s[:i], s[i+1:]
+		if lat, lon, err = latLongPair(a, b); err != nil {
+			return 0, 0, err
+		}
+		return lat, lon, nil
+	}
+	parts := strings.Fields(s)
+	for i := 1; i < len(parts); i++ {
+		a, b = strings.Join(parts[:i], " "), strings.Join(parts[i:], " ")
+		if lat, lon, err = latLongPair(a, b); err == nil {
+			return lat, lon, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("cannot parse coordinate pair [%s], unknown format", s)
+}
+
+// latLongPair ...
+func latLongPair(a, b string) (lat, lon float64, err error) {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	switch hemisphere(a) {
+	case East, West:
+		return 0, 0, fmt.Errorf("invalid latitude direction [%s]", a)
+	}
+	switch hemisphere(b) {
+	case North, South:
+		return 0, 0, fmt.Errorf("invalid longitude direction [%s]", b)
+	}
+	if lat, err = ParseLatLong(a); err != nil {
+		return 0, 0, err
+	}
+	if lon, err = ParseLatLong(b); err != nil {
+		return 0, 0, err
+	}
+	if lat < -90.0 || 90.0 < lat {
+		return 0, 0, fmt.Errorf("latitude is not in range (-90, 90)")
+	}
+	if lon < -180.0 || 180.0 < lon {
+		return 0, 0, fmt.Errorf("longitude is not in range (-180, 180)")
+	}
+	return lat, lon, nil
+}
+
+// hemisphere returns the leading or trailing direction letter, if any
+func hemisphere(s string) string {
+	if s == "" {
+		return ""
+	}
+	for _, d := range []string{North, South, East, West} {
+		if strings.HasPrefix(s, d) || strings.HasSuffix(s, d) {
+			return d
+		}
+	}
+	return ""
+}
+
+// FormatDMS returns l as degrees, minutes, seconds with a leading sign for southern and western
+// values, eg. [-9° 57' 26.520000"], the result round-trips through ParseDMS
 func FormatDMS(l float64) string {
-	val := math.Abs(l)
-	degrees := int(math.Floor(val))
-	minutes := int(math.Floor(60 * (val - float64(degrees))))
-	seconds := 3600 * (val - float64(degrees) - (float64(minutes) / 60))
-	return fmt.Sprintf("%d\u00B0 %d' %f\"", degrees, minutes, seconds)
+	sign := ""
+	if l < 0 {
+		sign = "-"
+	}
+	us := int64(math.Round(math.Abs(l) * 3600 * 1e6)) // round on the printed precision, avoids 60.000000 seconds
+	degrees := us / (3600 * 1e6)
+	minutes := us % (3600 * 1e6) / (60 * 1e6)
+	seconds := float64(us%(60*1e6)) / 1e6
+	if sign != "" && us == 0 {
+		sign = ""
+	}
+	return fmt.Sprintf("%s%d\u00B0 %d' %f\"", sign, degrees, minutes, seconds)
 }
 
 type Time struct {
@@ -796,6 +1270,9 @@ func ParseTime(s string) (Time, error) {
 	if s == "" {
 		return Time{}, nil
 	}
+	if len(s) < 6 {
+		return Time{}, fmt.Errorf("parse time: expected hhmmss format, got '%s'", s)
+	}
 	hour, _ := strconv.Atoi(s[:2])
 	minute, _ := strconv.Atoi(s[2:4])
 	second, _ := strconv.ParseFloat(s[4:], 64)
@@ -0,0 +1,469 @@
+package nmeanano
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const _epsilon = 1e-6
+
+// TestParseDMS ...
+func TestParseDMS(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{in: `53° 33' 51.25" N`, want: 53.564236},
+		{in: `53°33'51.25"S`, want: -53.564236},
+		{in: `S 53°33'51.25"`, want: -53.564236},
+		{in: `-9d57'26.5"`, want: -9.957361},
+		{in: `9° 57' 26.5" W`, want: -9.957361},
+		{in: `+9°57′26.5″E`, want: 9.957361},
+		{in: `9°`, want: 9},
+		{in: `9° 30'`, want: 9.5},
+		{in: `-9° 57' 26.5" W`, err: true},
+		{in: `9° 60'`, err: true},
+		{in: `9° 57' 60"`, err: true},
+		{in: `57' 26.5"`, err: true},
+		{in: `9° 26.5"`, err: true},
+		{in: `9.957`, err: true},
+		{in: ``, err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDMS(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseDMS(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || math.Abs(got-tt.want) > _epsilon {
+			t.Errorf("ParseDMS(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestParseLatLongPair ...
+func TestParseLatLongPair(t *testing.T) {
+	tests := []struct {
+		in       string
+		lat, lon float64
+		err      bool
+	}{
+		{in: "53.5642, 9.9573", lat: 53.5642, lon: 9.9573},
+		{in: "-33.8568; -151.2153", lat: -33.8568, lon: -151.2153},
+		{in: `53°33'51.3"N 9°57'26.5"E`, lat: 53.564250, lon: 9.957361},
+		{in: `33°51'24.5"S 151°12'55.1"W`, lat: -33.856806, lon: -151.215306},
+		{in: "5333.8542 N 00957.4411 E", lat: 53.564237, lon: 9.957352},
+		{in: "3351.4080 S 15112.9180 W", lat: -33.856800, lon: -151.215300},
+		{in: `9°57'26.5"E 53°33'51.3"N`, err: true},
+		{in: "91.0, 9.0", err: true},
+		{in: "53.0, 181.0", err: true},
+		{in: "north, east", err: true},
+	}
+	for _, tt := range tests {
+		lat, lon, err := ParseLatLongPair(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseLatLongPair(%q) = %v %v, want error", tt.in, lat, lon)
+			}
+			continue
+		}
+		if err != nil || math.Abs(lat-tt.lat) > _epsilon || math.Abs(lon-tt.lon) > _epsilon {
+			t.Errorf("ParseLatLongPair(%q) = %v %v, %v, want %v %v", tt.in, lat, lon, err, tt.lat, tt.lon)
+		}
+	}
+}
+
+// TestFormatRoundTrip ...
+func TestFormatRoundTrip(t *testing.T) {
+	for _, l := range []float64{0, 9.957, -9.957, 53.564236, -53.564236, 151.2153, -151.2153, 89.9999999999, -0.0000001, 179.99999999} {
+		dms := FormatDMS(l)
+		got, err := ParseDMS(dms)
+		if err != nil || math.Abs(got-l) > _epsilon {
+			t.Errorf("ParseDMS(FormatDMS(%v) = %q) = %v, %v", l, dms, got, err)
+		}
+		if got, err = ParseLatLong(dms); err != nil || math.Abs(got-l) > _epsilon {
+			t.Errorf("ParseLatLong(FormatDMS(%v) = %q) = %v, %v", l, dms, got, err)
+		}
+		if math.Abs(l) <= 90 {
+			gps := FormatLatGPS(l)
+			if got, err = ParseLatLong(gps); err != nil || math.Abs(got-l) > 1e-5 {
+				t.Errorf("ParseLatLong(FormatLatGPS(%v) = %q) = %v, %v", l, gps, got, err)
+			}
+		}
+		gps := FormatLonGPS(l)
+		if got, err = ParseLatLong(gps); err != nil || math.Abs(got-l) > 1e-5 {
+			t.Errorf("ParseLatLong(FormatLonGPS(%v) = %q) = %v, %v", l, gps, got, err)
+		}
+	}
+	if s := FormatLatGPS(-33.8568); s != "3351.4080 S" {
+		t.Errorf("FormatLatGPS(-33.8568) = %q", s)
+	}
+	if s := FormatLonGPS(9.957352); s != "00957.4411 E" {
+		t.Errorf("FormatLonGPS(9.957352) = %q", s)
+	}
+	if s := FormatDMS(-9.957361); s != `-9° 57' 26.499600"` {
+		t.Errorf("FormatDMS(-9.957361) = %q", s)
+	}
+}
+
+// groupLine returns a tag block prefixed sentence for the grouping
+func groupLine(t *testing.T, tags TagBlock, sentence string) BaseSentence {
+	t.Helper()
+	s, err := parseSentence(FormatTagBlock(tags, "$"+sentence+ChecksumSep+Checksum(sentence)), ChecksumRequired)
+	if err != nil {
+		t.Fatalf("parseSentence(%q): %v", sentence, err)
+	}
+	return s
+}
+
+// TestGroupAssembler ...
+func TestGroupAssembler(t *testing.T) {
+	var a GroupAssembler
+	first := groupLine(t, TagBlock{Grouping: "1-2-73874", Source: "SRC"}, "GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")
+	second := groupLine(t, TagBlock{Grouping: "2-2-73874"}, "GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W")
+	if _, ok, err := a.Add(first); ok || err != nil {
+		t.Fatalf("Add(first) = %v, %v, want pending", ok, err)
+	}
+	g, ok, err := a.Add(second)
+	if !ok || err != nil {
+		t.Fatalf("Add(second) = %v, %v, want complete group, pending %d", ok, err, a.Pending())
+	}
+	if g.ID != 73874 || g.Source != "SRC" || len(g.Sentences) != 2 || g.Sentences[0].Raw != first.Raw || g.Sentences[1].Raw != second.Raw {
+		t.Errorf("group = %+v", g)
+	}
+	if a.Pending() != 0 {
+		t.Errorf("Pending() = %d, want 0", a.Pending())
+	}
+
+	// out of order, source on a later part
+	a.Add(groupLine(t, TagBlock{Grouping: "2-2-7"}, "GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"))
+	if g, ok, _ = a.Add(groupLine(t, TagBlock{Grouping: "1-2-7", Source: "B"}, "GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")); !ok || g.Source != "B" {
+		t.Errorf("out of order group = %+v, %v", g, ok)
+	}
+}
+
+// TestGroupSources ...
+func TestGroupSources(t *testing.T) {
+	gga := "GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"
+	tests := []struct {
+		name  string
+		parts []TagBlock
+		want  []string // sources of the completed groups, in order
+	}{
+		{name: "same id, two sources", parts: []TagBlock{
+			{Grouping: "1-2-5", Source: "A"}, {Grouping: "1-2-5", Source: "B"},
+			{Grouping: "2-2-5", Source: "A"}, {Grouping: "2-2-5", Source: "B"},
+		}, want: []string{"A", "B"}},
+		{name: "later parts without source", parts: []TagBlock{
+			{Grouping: "1-2-5", Source: "A"}, {Grouping: "2-2-5"},
+			{Grouping: "1-2-5", Source: "B"}, {Grouping: "2-2-5"},
+		}, want: []string{"A", "B"}},
+		{name: "interleaved without source joins the latest", parts: []TagBlock{
+			{Grouping: "1-2-5", Source: "A"}, {Grouping: "1-2-5", Source: "B"},
+			{Grouping: "2-2-5"}, {Grouping: "2-2-5", Source: "A"},
+		}, want: []string{"B", "A"}},
+		{name: "no source at all", parts: []TagBlock{{Grouping: "1-2-5"}, {Grouping: "2-2-5"}}, want: []string{""}},
+	}
+	for _, tt := range tests {
+		var a GroupAssembler
+		var got []string
+		for _, tags := range tt.parts {
+			g, ok, err := a.Add(groupLine(t, tags, gga))
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if ok {
+				got = append(got, g.Source)
+				if len(g.Sentences) != 2 || g.Sentences[0].Raw == "" || g.Sentences[1].Raw == "" {
+					t.Errorf("%s: incomplete group %+v", tt.name, g)
+				}
+			}
+		}
+		if !slices.Equal(got, tt.want) || a.Pending() != 0 {
+			t.Errorf("%s: groups from %q, want %q, pending %d", tt.name, got, tt.want, a.Pending())
+		}
+	}
+}
+
+// TestGroupPending ...
+func TestGroupPending(t *testing.T) {
+	gga := "GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"
+	var a GroupAssembler
+	for id := 0; id < 2*maxPendingGroups; id++ {
+		a.Add(BaseSentence{Raw: "$" + gga, TagBlock: TagBlock{Grouping: fmt.Sprintf("1-2-%d", id), Source: "noise"}})
+	}
+	if n := a.Pending(); n != maxPendingGroups {
+		t.Errorf("Pending() = %d, want default cap %d", n, maxPendingGroups)
+	}
+	b := GroupAssembler{MaxPending: 2}
+	for _, id := range []string{"1", "2", "3"} {
+		b.Add(groupLine(t, TagBlock{Grouping: "1-2-" + id}, gga))
+	}
+	if _, ok, _ := b.Add(groupLine(t, TagBlock{Grouping: "2-2-1"}, gga)); ok {
+		t.Error("oldest group completed, want dropped")
+	}
+	if _, ok, _ := b.Add(groupLine(t, TagBlock{Grouping: "2-2-3"}, gga)); !ok {
+		t.Error("newest group dropped")
+	}
+}
+
+// TestGroupSize ...
+func TestGroupSize(t *testing.T) {
+	for _, grouping := range []string{"1-999999999999-1", "1-100-1", "0-2-1", "3-2-1", "1-0-1", "1-2", "a-2-1"} {
+		if _, _, _, err := (TagBlock{Grouping: grouping}).Group(); err == nil {
+			t.Errorf("Group(%q) = nil error, want error", grouping)
+		}
+		var a GroupAssembler
+		if _, ok, err := a.Add(BaseSentence{Raw: "$GPGGA", TagBlock: TagBlock{Grouping: grouping}}); ok || err == nil {
+			t.Errorf("Add(%q) = %v, %v, want error", grouping, ok, err)
+		}
+	}
+	if _, size, _, err := (TagBlock{Grouping: "99-99-1"}).Group(); err != nil || size != 99 {
+		t.Errorf("Group(99-99-1) = %d, %v", size, err)
+	}
+}
+
+// custom is a sentence returned by the test parsers
+type custom struct {
+	BaseSentence
+	parser string
+}
+
+// customParser returns a parser tagging its sentences with name
+func customParser(name string) ParserFunc {
+	return func(s BaseSentence) (Sentence, error) { return custom{BaseSentence: s, parser: name}, nil }
+}
+
+// parsedBy returns the name of the custom parser of the sentence, "" for builtin ones
+func parsedBy(t *testing.T, r *Registry, raw string) string {
+	t.Helper()
+	s, err := r.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", raw, err)
+	}
+	if c, ok := s.(custom); ok {
+		return c.parser
+	}
+	return ""
+}
+
+// TestRegistry ...
+func TestRegistry(t *testing.T) {
+	rmc := "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"
+	xyz := "$GPXYZ,1,2*" + Checksum("GPXYZ,1,2")
+	tests := []struct {
+		name string
+		op   func(r *Registry) error
+		rmc  string // parser of the rmc sentence, "" builtin
+		xyz  string // parser of the xyz sentence, "" unsupported
+	}{
+		{name: "empty"},
+		{name: "register type", op: func(r *Registry) error { return r.Register("XYZ", customParser("type")) }, xyz: "type"},
+		{name: "register duplicate", op: func(r *Registry) error {
+			r.Register("XYZ", customParser("first"))
+			if err := r.Register("XYZ", customParser("second")); err == nil {
+				return errors.New("duplicate key accepted")
+			}
+			return nil
+		}, xyz: "first"},
+		{name: "prefix before type", op: func(r *Registry) error {
+			r.Register("XYZ", customParser("type"))
+			return r.Register("GPXYZ", customParser("prefix"))
+		}, xyz: "prefix"},
+		{name: "custom before builtin", op: func(r *Registry) error { return r.Register("RMC", customParser("rmc")) }, rmc: "rmc"},
+		{name: "override", op: func(r *Registry) error {
+			if r.Override("XYZ", customParser("first")) {
+				return errors.New("override of a free key reported a replace")
+			}
+			if !r.Override("XYZ", customParser("second")) {
+				return errors.New("override of a taken key reported no replace")
+			}
+			return nil
+		}, xyz: "second"},
+		{name: "unregister", op: func(r *Registry) error {
+			r.Register("RMC", customParser("rmc"))
+			r.Register("XYZ", customParser("xyz"))
+			if !r.Unregister("RMC") || !r.Unregister("XYZ") {
+				return errors.New("unregister of a taken key reported none")
+			}
+			if r.Unregister("XYZ") {
+				return errors.New("unregister of a free key reported one")
+			}
+			return r.Register("XYZ", customParser("again"))
+		}, xyz: "again"},
+	}
+	for _, tt := range tests {
+		for _, r := range []*Registry{NewRegistry(), {}} {
+			if tt.op != nil {
+				if err := tt.op(r); err != nil {
+					t.Errorf("%s: %v", tt.name, err)
+					continue
+				}
+			}
+			if got := parsedBy(t, r, rmc); got != tt.rmc {
+				t.Errorf("%s: rmc parsed by %q, want %q", tt.name, got, tt.rmc)
+			}
+			s, err := r.Parse(xyz)
+			if tt.xyz == "" {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Errorf("%s: Parse(xyz) = %v, %v, want ErrUnsupported", tt.name, s, err)
+				}
+				continue
+			}
+			if c, ok := s.(custom); err != nil || !ok || c.parser != tt.xyz {
+				t.Errorf("%s: Parse(xyz) = %v, %v, want parser %q", tt.name, s, err, tt.xyz)
+			}
+		}
+	}
+}
+
+// TestRegistryDefault ...
+func TestRegistryDefault(t *testing.T) {
+	raw := "$GPXYZ,1*" + Checksum("GPXYZ,1")
+	if err := RegisterParser("XYZ", customParser("default")); err != nil {
+		t.Fatal(err)
+	}
+	if got := parsedBy(t, DefaultRegistry, raw); got != "default" {
+		t.Errorf("parsed by %q, want default", got)
+	}
+	if !UnregisterParser("XYZ") {
+		t.Error("UnregisterParser reported no parser")
+	}
+	if _, err := Parse(raw); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Parse after unregister = %v, want ErrUnsupported", err)
+	}
+}
+
+// TestRegistryConcurrent registers, overrides and unregisters while parsing, run with -race
+func TestRegistryConcurrent(t *testing.T) {
+	r := NewRegistry()
+	raw := "$GPXYZ,1*" + Checksum("GPXYZ,1")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("X%02d", j%10)
+				r.Register(key, customParser(key))
+				r.Override("XYZ", customParser("xyz"))
+				r.Unregister(key)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if _, err := r.Parse(raw); err != nil && !errors.Is(err, ErrUnsupported) {
+					t.Errorf("Parse: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := parsedBy(t, r, raw); got != "xyz" {
+		t.Errorf("parsed by %q, want xyz", got)
+	}
+}
+
+// _fuzzSeeds are valid and malformed sentences
+var _fuzzSeeds = []string{
+	"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A",
+	"$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47",
+	"$GPGSV,2,1,08,01,40,083,46,02,17,308,41,12,07,344,39,14,22,228,45*75",
+	"$GNGSA,A,3,80,71,73,79,69,,,,,,,,1.83,1.09,1.47*17",
+	" $GNGSA,A,3,80,71,73,79,69,,,,,,,,1.83,1.09,1.47*17",
+	"$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48",
+	"$GNGNS,014035.00,4332.69262,S,17235.48549,E,RR,13,0.9,25.63,11.24,,*70",
+	"!AIVDM,1,1,,A,13aG?P0P00PD;88MD5MTDww@2<0L,0*23",
+	`\s:SRC,c:1577923200,g:1-2-73874*1A\$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6a` + "\r\n",
+	"$GPRMC,123519,A*", "$GPGGA,780,N,E,1,08,0,*47", "$*00", "$,GPRMC*00", "$", "", `\`, `\\`, "$GPRMC*zz", "$GPGSV,9,9,99*",
+}
+
+// FuzzValidateChecksum ...
+func FuzzValidateChecksum(f *testing.F) {
+	for _, s := range _fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		for _, mode := range []ChecksumMode{ChecksumRequired, ChecksumOptional} {
+			err := ValidateChecksum(raw, mode)
+			if (err == nil) != ChecksumValid(raw, mode) {
+				t.Fatalf("ValidateChecksum and ChecksumValid disagree on %q [%v]", raw, err)
+			}
+		}
+		if ChecksumValid(raw, ChecksumRequired) && !ChecksumValid(raw, ChecksumOptional) {
+			t.Fatalf("%q valid with required but not with optional checksum", raw)
+		}
+		_, _, fields, _, err := splitChecksum(raw, ChecksumOptional)
+		if err != nil || strings.Contains(fields, ChecksumSep) {
+			return
+		}
+		if sealed := "$" + fields + ChecksumSep + Checksum(fields); !ChecksumValid(sealed, ChecksumRequired) {
+			t.Fatalf("recomputed checksum rejected [%q]", sealed)
+		}
+	})
+}
+
+// FuzzParse ...
+func FuzzParse(f *testing.F) {
+	for _, s := range _fuzzSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		for _, mode := range []ChecksumMode{ChecksumRequired, ChecksumOptional} {
+			s, err := ParseMode(raw, mode)
+			if err != nil {
+				continue
+			}
+			if s == nil || s.DataType() == "" {
+				t.Fatalf("ParseMode(%q, %d) = %#v without data type", raw, mode, s)
+			}
+			if !ChecksumValid(raw, mode) {
+				t.Fatalf("ParseMode(%q, %d) accepted a sentence ChecksumValid rejects", raw, mode)
+			}
+		}
+	})
+}
+
+// TestParseMode checks that Parse and the checksum validation agree on the checksum mode and whitespace
+func TestParseMode(t *testing.T) {
+	const gsa = "$GNGSA,A,3,80,71,73,79,69,,,,,,,,1.83,1.09,1.47"
+	tags := `\s:SRC*` + Checksum("s:SRC") + `\`
+	tests := []struct {
+		raw      string
+		required bool // valid with ChecksumRequired
+		optional bool // valid with ChecksumOptional
+	}{
+		{raw: gsa + "*17", required: true, optional: true},
+		{raw: " " + gsa + "*17\r\n", required: true, optional: true},
+		{raw: "\t" + tags + gsa + "*17 ", required: true, optional: true},
+		{raw: gsa, optional: true},
+		{raw: "  " + gsa + "\r\n", optional: true},
+		{raw: gsa + "*18"},
+		{raw: tags + " " + gsa + "*17"},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[ChecksumMode]bool{ChecksumRequired: tt.required, ChecksumOptional: tt.optional} {
+			s, err := ParseMode(tt.raw, mode)
+			if (err == nil) != want || ChecksumValid(tt.raw, mode) != want {
+				t.Errorf("ParseMode(%q, %d) = %v, ChecksumValid %v, want valid %v", tt.raw, mode, err, ChecksumValid(tt.raw, mode), want)
+				continue
+			}
+			if err == nil && s.(GSA).Raw != strings.TrimSpace(tt.raw[strings.IndexByte(tt.raw, '$'):]) {
+				t.Errorf("ParseMode(%q, %d) raw %q", tt.raw, mode, s.(GSA).Raw)
+			}
+		}
+	}
+	if _, err := Parse(gsa); err == nil {
+		t.Errorf("Parse(%q) without checksum, want error", gsa)
+	}
+}
@@ -0,0 +1,80 @@
+// package rtcm3 detects RTCM 3.x correction frames interleaved with NMEA on one stream and keeps per-type statistics
+package rtcm3
+
+// import
+import (
+	"sync"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Frame
+//
+
+// RTCM3 frame constants
+const (
+	Preamble   = 0xD3    // frame start
+	TypeRTCM3  = "RTCM3" // sentence type of the Status summary
+	TalkerRTCM = "RTCM"  // talker id of the Status summary
+	_headerLen = 3       // preamble, reserved, length
+	_crcLen    = 3       // crc-24q
+	_maxLen    = 1023    // max payload length [10 bit]
+)
+
+// Frame is a single RTCM3 message
+type Frame struct {
+	Type    int    // message type number, eg. 1077
+	Payload []byte // message payload, without header and crc
+}
+
+// Match checks for a complete, crc verified frame at the start of data. It returns the frame length,
+// 0 and true if more data is needed, or false if data does not start with a valid frame.
+func Match(data []byte, atEOF bool) (n int, ok bool) { return match(data, atEOF) }
+
+// ParseFrame validates and parses a raw RTCM3 frame
+func ParseFrame(raw []byte) (Frame, error) { return parseFrame(raw) }
+
+// Name returns a short description of the message type, eg. [GPS MSM7]
+func Name(msgType int) string { return name(msgType) }
+
+//
+// Statistics
+//
+
+// TypeStat holds the counters of a single message type
+type TypeStat struct {
+	Type  int       // message type number
+	Name  string    // message type description
+	Count uint64    // frames seen
+	Rate  float64   // frames per second
+	First time.Time // first seen
+	Last  time.Time // last seen
+}
+
+// Stats collects per message type counters, safe for concurrent use, the zero value is ready to use
+type Stats struct {
+	mu    sync.Mutex
+	types map[int]*TypeStat
+	last  time.Time
+}
+
+// Add counts a received frame
+func (s *Stats) Add(f Frame, t time.Time) { s.add(f, t) }
+
+// Snapshot returns the counters of all seen message types, ordered by type number
+func (s *Stats) Snapshot() []TypeStat { return s.snapshot() }
+
+// Age returns the correction age, the time since the last received frame, false if none was received yet
+func (s *Stats) Age(now time.Time) (time.Duration, bool) { return s.age(now) }
+
+// Status is the statistics snapshot as sentence, for display pipelines fed with nmeanano sentences
+type Status struct {
+	nmeanano.BaseSentence
+	Types []TypeStat
+	Last  time.Time
+}
+
+// Status returns the current statistics snapshot as sentence
+func (s *Stats) Status() Status { return s.status() }
@@ -0,0 +1,168 @@
+// package rtcm3 ...
+package rtcm3
+
+// import
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Frame
+//
+
+// match ...
+func match(data []byte, atEOF bool) (int, bool) {
+	if len(data) == 0 || data[0] != Preamble {
+		return 0, false
+	}
+	if len(data) < _headerLen {
+		return 0, !atEOF
+	}
+	if data[1]&0xFC != 0 { // reserved bits must be zero
+		return 0, false
+	}
+	n := _headerLen + length(data) + _crcLen
+	if len(data) < n {
+		return 0, !atEOF
+	}
+	if crc24q(data[:n-_crcLen]) != crcOf(data[n-_crcLen:n]) {
+		return 0, false
+	}
+	return n, true
+}
+
+// length returns the 10 bit payload length of the frame header
+func length(data []byte) int { return int(data[1]&0x03)<<8 | int(data[2]) }
+
+// crcOf ...
+func crcOf(b []byte) uint32 { return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]) }
+
+// parseFrame ...
+func parseFrame(raw []byte) (Frame, error) {
+	n, ok := match(raw, true)
+	if !ok || n != len(raw) {
+		return Frame{}, fmt.Errorf("rtcm3: invalid frame [preamble, length or crc]")
+	}
+	payload := raw[_headerLen : n-_crcLen]
+	if len(payload) < 2 {
+		return Frame{}, fmt.Errorf("rtcm3: frame payload too short [%d bytes]", len(payload))
+	}
+	return Frame{Type: int(payload[0])<<4 | int(payload[1]>>4), Payload: payload}, nil
+}
+
+// crc24q calculates the qualcomm crc-24q used by rtcm3
+func crc24q(b []byte) uint32 {
+	var crc uint32
+	for _, x := range b {
+		crc ^= uint32(x) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
+
+// _names ...
+var _names = map[int]string{
+	1001: "GPS L1 RTK",
+	1002: "GPS L1 RTK ext",
+	1003: "GPS L1/L2 RTK",
+	1004: "GPS L1/L2 RTK ext",
+	1005: "Station ARP",
+	1006: "Station ARP + height",
+	1007: "Antenna descriptor",
+	1008: "Antenna descriptor + serial",
+	1009: "GLONASS L1 RTK",
+	1010: "GLONASS L1 RTK ext",
+	1011: "GLONASS L1/L2 RTK",
+	1012: "GLONASS L1/L2 RTK ext",
+	1019: "GPS ephemeris",
+	1020: "GLONASS ephemeris",
+	1033: "Receiver + antenna descriptor",
+	1042: "BeiDou ephemeris",
+	1045: "Galileo F/NAV ephemeris",
+	1046: "Galileo I/NAV ephemeris",
+	1230: "GLONASS code-phase biases",
+}
+
+// _msm ...
+var _msm = map[int]string{107: "GPS", 108: "GLONASS", 109: "Galileo", 110: "SBAS", 111: "QZSS", 112: "BeiDou", 113: "NavIC"}
+
+// name ...
+func name(t int) string {
+	if n, ok := _names[t]; ok {
+		return n
+	}
+	if gnss, ok := _msm[t/10]; ok && t%10 >= 1 && t%10 <= 7 {
+		return gnss + " MSM" + strconv.Itoa(t%10)
+	}
+	return "unknown"
+}
+
+//
+// Statistics
+//
+
+// add ...
+func (s *Stats) add(f Frame, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.types == nil {
+		s.types = make(map[int]*TypeStat)
+	}
+	ts, ok := s.types[f.Type]
+	if !ok {
+		ts = &TypeStat{Type: f.Type, Name: name(f.Type), First: t}
+		s.types[f.Type] = ts
+	}
+	ts.Count++
+	ts.Last = t
+	if d := ts.Last.Sub(ts.First).Seconds(); d > 0 {
+		ts.Rate = float64(ts.Count-1) / d
+	}
+	s.last = t
+}
+
+// snapshot ...
+func (s *Stats) snapshot() []TypeStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]TypeStat, 0, len(s.types))
+	for _, ts := range s.types {
+		list = append(list, *ts)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list
+}
+
+// age ...
+func (s *Stats) age(now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last.IsZero() {
+		return 0, false
+	}
+	return now.Sub(s.last), true
+}
+
+// status ...
+func (s *Stats) status() Status {
+	types := s.snapshot()
+	s.mu.Lock()
+	last := s.last
+	s.mu.Unlock()
+	raw := fmt.Sprintf("[rtcm3] [%d message types]", len(types))
+	return Status{
+		BaseSentence: nmeanano.BaseSentence{Talker: TalkerRTCM, Type: TypeRTCM3, Raw: raw},
+		Types:        types,
+		Last:         last,
+	}
+}
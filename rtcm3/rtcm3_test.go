@@ -0,0 +1,149 @@
+package rtcm3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// _1005 is the message 1005 example of the RTCM 10403 standard
+var _1005 = []byte{
+	0xD3, 0x00, 0x13, 0x3E, 0xD7, 0xD3, 0x02, 0x02, 0x98, 0x0E, 0xDE, 0xEF, 0x34,
+	0xB4, 0xBD, 0x62, 0xAC, 0x09, 0x41, 0x98, 0x6F, 0x33, 0x36, 0x0B, 0x98,
+}
+
+// frame returns a crc protected frame of the message type, padded to size payload bytes
+func frame(msgType, size int) []byte {
+	payload := make([]byte, size)
+	copy(payload, []byte{byte(msgType >> 4), byte(msgType << 4)})
+	raw := append([]byte{Preamble, byte(size >> 8), byte(size)}, payload...)
+	crc := crc24q(raw)
+	return append(raw, byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+// TestCRC24Q ...
+func TestCRC24Q(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want uint32
+	}{
+		{in: []byte("123456789"), want: 0xCDE703}, // crc-24q check value
+		{in: _1005[:len(_1005)-_crcLen], want: 0x360B98},
+		{in: nil, want: 0},
+	}
+	for _, tt := range tests {
+		if got := crc24q(tt.in); got != tt.want {
+			t.Errorf("crc24q(%X) = %06X, want %06X", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestMatch ...
+func TestMatch(t *testing.T) {
+	corrupt := bytes.Clone(_1005)
+	corrupt[10] ^= 0x01
+	reserved := bytes.Clone(_1005)
+	reserved[1] |= 0x04
+	tests := []struct {
+		name  string
+		data  []byte
+		atEOF bool
+		n     int
+		ok    bool
+	}{
+		{name: "frame", data: _1005, n: len(_1005), ok: true},
+		{name: "frame at eof", data: _1005, atEOF: true, n: len(_1005), ok: true},
+		{name: "frame and text", data: append(bytes.Clone(_1005), "$GPRMC"...), n: len(_1005), ok: true},
+		{name: "max length", data: frame(1077, _maxLen), n: _maxLen + _headerLen + _crcLen, ok: true},
+		{name: "preamble only", data: _1005[:1], ok: true},
+		{name: "truncated", data: _1005[:len(_1005)-1], ok: true},
+		{name: "truncated at eof", data: _1005[:len(_1005)-1], atEOF: true},
+		{name: "header at eof", data: _1005[:2], atEOF: true},
+		{name: "corrupt payload", data: corrupt},
+		{name: "corrupt crc", data: append(bytes.Clone(_1005[:len(_1005)-1]), 0x99)},
+		{name: "reserved bits", data: reserved},
+		{name: "no preamble", data: []byte("$GPRMC,1*00\n")},
+		{name: "empty", data: nil},
+	}
+	for _, tt := range tests {
+		if n, ok := Match(tt.data, tt.atEOF); n != tt.n || ok != tt.ok {
+			t.Errorf("%s: Match = %d, %v, want %d, %v", tt.name, n, ok, tt.n, tt.ok)
+		}
+	}
+}
+
+// TestParseFrame ...
+func TestParseFrame(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []byte
+		typ  int
+		err  bool
+	}{
+		{name: "1005", raw: _1005, typ: 1005},
+		{name: "msm7", raw: frame(1077, 20), typ: 1077},
+		{name: "type 4095", raw: frame(4095, 2), typ: 4095},
+		{name: "trailing data", raw: append(bytes.Clone(_1005), 0xD3), err: true},
+		{name: "truncated", raw: _1005[:len(_1005)-1], err: true},
+		{name: "payload too short", raw: frame(1005, 1), err: true},
+		{name: "empty", raw: nil, err: true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFrame(tt.raw)
+		if (err != nil) != tt.err || f.Type != tt.typ {
+			t.Errorf("%s: ParseFrame = %d, %v, want %d, error %v", tt.name, f.Type, err, tt.typ, tt.err)
+		}
+		if err == nil && len(f.Payload) != len(tt.raw)-_headerLen-_crcLen {
+			t.Errorf("%s: payload %d bytes", tt.name, len(f.Payload))
+		}
+	}
+}
+
+// TestName ...
+func TestName(t *testing.T) {
+	for typ, want := range map[int]string{
+		1005: "Station ARP",
+		1077: "GPS MSM7",
+		1084: "GLONASS MSM4",
+		1127: "BeiDou MSM7",
+		1078: "unknown",
+		1070: "unknown",
+		9999: "unknown",
+	} {
+		if got := Name(typ); got != want {
+			t.Errorf("Name(%d) = %q, want %q", typ, got, want)
+		}
+	}
+}
+
+// TestStats ...
+func TestStats(t *testing.T) {
+	var s Stats
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	if age, ok := s.Age(start); ok || age != 0 {
+		t.Errorf("Age without frames = %v, %v, want 0, false", age, ok)
+	}
+	for i := 0; i < 5; i++ {
+		s.Add(Frame{Type: 1077}, start.Add(time.Duration(i)*time.Second))
+	}
+	s.Add(Frame{Type: 1005}, start.Add(4*time.Second))
+	if age, ok := s.Age(start.Add(4 * time.Second)); !ok || age != 0 {
+		t.Errorf("Age just received = %v, %v, want 0, true", age, ok)
+	}
+	if age, ok := s.Age(start.Add(7 * time.Second)); !ok || age != 3*time.Second {
+		t.Errorf("Age = %v, %v, want 3s, true", age, ok)
+	}
+	snap := s.Snapshot()
+	if len(snap) != 2 || snap[0].Type != 1005 || snap[1].Type != 1077 {
+		t.Fatalf("Snapshot = %+v", snap)
+	}
+	if msm := snap[1]; msm.Count != 5 || msm.Rate != 1 || msm.Name != "GPS MSM7" || !msm.First.Equal(start) {
+		t.Errorf("1077 = %+v", msm)
+	}
+	if arp := snap[0]; arp.Count != 1 || arp.Rate != 0 {
+		t.Errorf("1005 = %+v", arp)
+	}
+	if st := s.Status(); len(st.Types) != 2 || !st.Last.Equal(start.Add(4*time.Second)) || st.DataType() != TypeRTCM3 {
+		t.Errorf("Status = %+v", st)
+	}
+}
@@ -0,0 +1,60 @@
+package gpsinfo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"paepcke.de/gpsinfo/gpsfeed"
+)
+
+// scan probes all candidate devices and reports the gps receivers found
+func scan(ctx context.Context, window time.Duration) error {
+	defer outPlain(_OFF + "\n") // restore terminal colors
+	out("[info] [scan] [probing devices]")
+	list := gpsfeed.Discover(ctx, window)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	out(renderScan(list))
+	for _, d := range list {
+		if d.Found() {
+			return nil
+		}
+	}
+	return fmt.Errorf("scan: no gps receiver found [%d devices probed]", len(list))
+}
+
+// renderScan ...
+func renderScan(list []gpsfeed.Discovery) string {
+	var b strings.Builder
+	fmt.Fprint(&b, _sectionLine)
+	for _, d := range list {
+		state := _defaultsShort
+		switch {
+		case d.Err != nil:
+			state = _GREY + "[" + d.Err.Error() + "]" + _OFF
+		case d.Found():
+			state = _ok
+		}
+		fmt.Fprintf(&b, "DEVICE               : %s%s%s %s\n", _BLUE, d.Device, _OFF, state)
+		if !d.Found() {
+			continue
+		}
+		for _, alias := range d.Aliases {
+			fmt.Fprintf(&b, " + Alias             : %s%s%s\n", _BLUE, alias, _OFF)
+		}
+		if d.USB != (gpsfeed.USBMatch{}) {
+			fmt.Fprintf(&b, " + USB               : %s%s%s\n", _BLUE, d.USB, _OFF)
+		}
+		model := _defaultsShort
+		if d.Model != "" {
+			model = _BLUE + d.Model + _OFF
+		}
+		fmt.Fprintf(&b, " + Model             : %s\n", model)
+		fmt.Fprintf(&b, " + Sentences         : Valid %s%d/%d%s Rate %s%.1f%s [1/s] Talkers %s%s%s\n", _BLUE, d.Valid, d.Total, _OFF, _BLUE, d.Rate, _OFF, _BLUE, strings.Join(d.Talkers, " "), _OFF)
+	}
+	fmt.Fprint(&b, _sectionLine)
+	return b.String()
+}
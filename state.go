@@ -0,0 +1,53 @@
+package gpsinfo
+
+import (
+	"context"
+	"time"
+
+	"paepcke.de/gpsinfo/epoch"
+	"paepcke.de/gpsinfo/gpsfeed"
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+// subscribe runs the device feed without display and publishes a State per epoch until ctx is done
+func subscribe(ctx context.Context, dev *gpsfeed.GpsDevice, size int) <-chan State {
+	updates := make(chan State, size)
+	go func() {
+		defer close(updates)
+		for {
+			if err := dev.OpenContext(ctx); err != nil {
+				return
+			}
+			channelGpsFrames := make(chan nmeanano.Sentence, 50)
+			stats := &feedStats{}
+			go feed(dev, stats, channelGpsFrames)
+			epochs := &epoch.Assembler{MaxAge: maxDiff}
+			for s := range channelGpsFrames {
+				fix, ok := epochs.Add(s, time.Now())
+				if !ok {
+					continue
+				}
+				select {
+				case updates <- newState(dev, fix, stats):
+				case <-ctx.Done():
+				}
+			}
+			dev.Close()
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return updates
+}
+
+// newState ...
+func newState(dev *gpsfeed.GpsDevice, fix epoch.Fix, stats *feedStats) State {
+	st := State{Fix: fix, Device: dev.FileIO, Dropped: stats.dropped.Load()}
+	if fix.Date.Valid && fix.Time.Valid {
+		st.GPSTime = time.Date(2000+fix.Date.YY, time.Month(fix.Date.MM), fix.Date.DD,
+			fix.Time.Hour, fix.Time.Minute, fix.Time.Second, fix.Time.Millisecond*1000*1000, time.UTC)
+		st.Offset = st.GPSTime.Sub(fix.Opened)
+	}
+	return st
+}
@@ -0,0 +1,54 @@
+package gpsinfo
+
+import (
+	"sync/atomic"
+	"time"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Throttle
+//
+
+// throttle limits the render rate, the builder applies every epoch regardless
+type throttle struct {
+	interval time.Duration // min time between renders
+	last     time.Time     // last render
+}
+
+// newThrottle returns a throttle for max rate renders per second [<= 0: every epoch]
+func newThrottle(rate float64) *throttle {
+	t := &throttle{}
+	if rate > 0 {
+		t.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return t
+}
+
+// due reports if a render is due at now
+func (t *throttle) due(now time.Time) bool {
+	if now.Sub(t.last) < t.interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+//
+// Feed
+//
+
+// feedStats are the counters shared by the feeder and the builder
+type feedStats struct {
+	dropped atomic.Uint64 // sentences dropped, builder busy
+}
+
+// send forwards s to the builder without ever blocking the feeder, counts the drops
+func (f *feedStats) send(channelGpsFrames chan nmeanano.Sentence, s nmeanano.Sentence) {
+	select {
+	case channelGpsFrames <- s:
+	default:
+		f.dropped.Add(1)
+	}
+}
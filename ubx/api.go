@@ -0,0 +1,106 @@
+// package ubx matches and decodes u-blox UBX binary frames interleaved with NMEA text on one stream
+package ubx
+
+// import
+import (
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Frame
+//
+
+// UBX frame constants
+const (
+	Sync1      = 0xB5 // first sync char
+	Sync2      = 0x62 // second sync char
+	ClassNAV   = 0x01 // navigation results
+	ClassACK   = 0x05 // ack/nak
+	ClassCFG   = 0x06 // configuration
+	ClassMON   = 0x0A // monitoring
+	IDAckNak   = 0x00 // ACK-NAK
+	IDAckAck   = 0x01 // ACK-ACK
+	IDNavPVT   = 0x07 // NAV-PVT
+	IDNavTime  = 0x21 // NAV-TIMEUTC
+	IDNavSat   = 0x35 // NAV-SAT
+	IDMonHW    = 0x09 // MON-HW
+	TypeMonHW  = "MONHW"
+	TalkerUBX  = "UBX"
+	_headerLen = 6    // sync, class, id, length
+	_frameOver = 8    // header + checksum
+	_maxLen    = 8192 // max accepted payload length, longer is treated as false sync
+)
+
+// Frame is a single UBX message
+type Frame struct {
+	Class   byte
+	ID      byte
+	Payload []byte
+}
+
+// Match checks for a complete, checksum verified frame at the start of data. It returns the frame
+// length, 0 and true if more data is needed, or false if data does not start with a valid frame.
+func Match(data []byte, atEOF bool) (n int, ok bool) { return match(data, atEOF) }
+
+// IsFrame reports if the token is a UBX frame [sync chars]
+func IsFrame(token []byte) bool { return len(token) > 1 && token[0] == Sync1 && token[1] == Sync2 }
+
+// ParseFrame validates and parses a raw UBX frame
+func ParseFrame(raw []byte) (Frame, error) { return parseFrame(raw) }
+
+// Encode returns the raw, checksummed UBX frame
+func Encode(f Frame) []byte { return encode(f) }
+
+// Ack is a decoded ACK-ACK or ACK-NAK
+type Ack struct {
+	Class byte // class of the acknowledged message
+	ID    byte // id of the acknowledged message
+	Ack   bool // ACK-ACK, false for ACK-NAK
+}
+
+// ParseAck decodes an ACK-ACK or ACK-NAK frame
+func ParseAck(f Frame) (Ack, error) { return parseAck(f) }
+
+//
+// Decoder
+//
+
+// GNSS ids of NAV-SAT and CFG-GNSS
+const (
+	GNSSGPS     = 0
+	GNSSSBAS    = 1
+	GNSSGalileo = 2
+	GNSSBeiDou  = 3
+	GNSSIMES    = 4
+	GNSSQZSS    = 5
+	GNSSGLONASS = 6
+)
+
+// MonHW holds the MON-HW antenna and jamming status
+type MonHW struct {
+	nmeanano.BaseSentence
+	AntennaStatus string // INIT, DONTKNOW, OK, SHORT, OPEN
+	AntennaPower  string // OFF, ON, DONTKNOW
+	JammingState  string // UNKNOWN, OK, WARNING, CRITICAL
+	JamInd        int64  // cw jamming indicator [0-255]
+	NoisePerMS    int64  // noise level
+	AGCCount      int64  // agc monitor [0-8191]
+}
+
+// Decoder merges decoded UBX messages into the nmeanano sentence types. Satellites use the
+// u-blox extended NMEA numbering [gps 1-32, sbas 33-64, glonass 65-96, imes 173-182, qzss 193-202,
+// galileo 301-336, beidou 401-437], unique across constellations.
+type Decoder struct {
+	rmc nmeanano.RMC
+	gga nmeanano.GGA
+	gsa nmeanano.GSA
+	gsv nmeanano.GSV
+	vtg nmeanano.VTG
+	hw  MonHW
+}
+
+// NewDecoder returns a new, empty Decoder
+func NewDecoder() *Decoder { return newDecoder() }
+
+// Decode returns the sentences updated by the frame, unknown messages return none
+func (d *Decoder) Decode(f Frame) ([]nmeanano.Sentence, error) { return d.decode(f) }
@@ -0,0 +1,293 @@
+// package ubx ...
+package ubx
+
+// import
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+//
+// Frame
+//
+
+// match ...
+func match(data []byte, atEOF bool) (int, bool) {
+	if len(data) == 0 || data[0] != Sync1 || len(data) > 1 && data[1] != Sync2 {
+		return 0, false
+	}
+	if len(data) < _headerLen {
+		return 0, !atEOF
+	}
+	l := int(binary.LittleEndian.Uint16(data[4:]))
+	if l > _maxLen {
+		return 0, false
+	}
+	if len(data) < l+_frameOver {
+		return 0, !atEOF
+	}
+	if _, err := parseFrame(data[:l+_frameOver]); err != nil {
+		return 0, false
+	}
+	return l + _frameOver, true
+}
+
+// checksum calculates the 8 bit fletcher checksum over class, id, length and payload
+func checksum(b []byte) (a, c byte) {
+	for _, x := range b {
+		a += x
+		c += a
+	}
+	return a, c
+}
+
+// parseFrame ...
+func parseFrame(raw []byte) (Frame, error) {
+	if len(raw) < _frameOver || !IsFrame(raw) {
+		return Frame{}, fmt.Errorf("ubx: frame too short or missing sync chars")
+	}
+	l := int(binary.LittleEndian.Uint16(raw[4:]))
+	if len(raw) != l+_frameOver {
+		return Frame{}, fmt.Errorf("ubx: frame length mismatch [%d != %d]", len(raw), l+_frameOver)
+	}
+	a, c := checksum(raw[2 : _headerLen+l])
+	if a != raw[_headerLen+l] || c != raw[_headerLen+l+1] {
+		return Frame{}, fmt.Errorf("ubx: frame checksum mismatch")
+	}
+	return Frame{Class: raw[2], ID: raw[3], Payload: raw[_headerLen : _headerLen+l]}, nil
+}
+
+// parseAck ...
+func parseAck(f Frame) (Ack, error) {
+	if f.Class != ClassACK || (f.ID != IDAckAck && f.ID != IDAckNak) {
+		return Ack{}, fmt.Errorf("ubx: %s is not an ACK-ACK or ACK-NAK", name(f))
+	}
+	if len(f.Payload) < 2 {
+		return Ack{}, errShort(f)
+	}
+	return Ack{Class: f.Payload[0], ID: f.Payload[1], Ack: f.ID == IDAckAck}, nil
+}
+
+// encode ...
+func encode(f Frame) []byte {
+	raw := make([]byte, _headerLen, len(f.Payload)+_frameOver)
+	raw[0], raw[1], raw[2], raw[3] = Sync1, Sync2, f.Class, f.ID
+	binary.LittleEndian.PutUint16(raw[4:], uint16(len(f.Payload)))
+	raw = append(raw, f.Payload...)
+	a, c := checksum(raw[2:])
+	return append(raw, a, c)
+}
+
+//
+// Decoder
+//
+
+var (
+	_antennaStatus = []string{"INIT", "DONTKNOW", "OK", "SHORT", "OPEN"}
+	_antennaPower  = []string{"OFF", "ON", "DONTKNOW"}
+	_jammingState  = []string{"UNKNOWN", "OK", "WARNING", "CRITICAL"}
+)
+
+// newDecoder ...
+func newDecoder() *Decoder {
+	d := &Decoder{}
+	d.rmc.BaseSentence = base(nmeanano.TypeRMC)
+	d.rmc.Validity = nmeanano.InvalidRMC
+	d.gga.BaseSentence = base(nmeanano.TypeGGA)
+	d.gga.FixQuality = nmeanano.Invalid
+	d.gsa.BaseSentence = base(nmeanano.TypeGSA)
+	d.gsv.BaseSentence = base(nmeanano.TypeGSV)
+	d.vtg.BaseSentence = base(nmeanano.TypeVTG)
+	d.hw.BaseSentence = base(TypeMonHW)
+	return d
+}
+
+// base ...
+func base(typ string) nmeanano.BaseSentence {
+	return nmeanano.BaseSentence{Talker: TalkerUBX, Type: typ}
+}
+
+// name returns the message name, eg. NAV-PVT
+func name(f Frame) string {
+	switch {
+	case f.Class == ClassNAV && f.ID == IDNavPVT:
+		return "NAV-PVT"
+	case f.Class == ClassNAV && f.ID == IDNavSat:
+		return "NAV-SAT"
+	case f.Class == ClassNAV && f.ID == IDNavTime:
+		return "NAV-TIMEUTC"
+	case f.Class == ClassMON && f.ID == IDMonHW:
+		return "MON-HW"
+	case f.Class == ClassACK && f.ID == IDAckAck:
+		return "ACK-ACK"
+	case f.Class == ClassACK && f.ID == IDAckNak:
+		return "ACK-NAK"
+	}
+	return fmt.Sprintf("0x%02X-0x%02X", f.Class, f.ID)
+}
+
+// raw describes the source message of a decoded sentence
+func raw(f Frame) string {
+	return fmt.Sprintf("[ubx] [%s] [%d bytes]", name(f), len(f.Payload))
+}
+
+// decode ...
+func (d *Decoder) decode(f Frame) ([]nmeanano.Sentence, error) {
+	p := f.Payload
+	r := raw(f)
+	switch {
+	case f.Class == ClassNAV && f.ID == IDNavPVT:
+		if len(p) < 92 {
+			return nil, errShort(f)
+		}
+		valid := p[11]
+		if valid&0x01 != 0 {
+			d.rmc.Date = nmeanano.Date{Valid: true, DD: int(p[7]), MM: int(p[6]), YY: int(binary.LittleEndian.Uint16(p[4:])) % 100}
+		}
+		if valid&0x02 != 0 {
+			d.setTime(p[8], p[9], p[10], int32(binary.LittleEndian.Uint32(p[16:])))
+		}
+		fixType, flags := p[20], p[21]
+		d.rmc.Validity = nmeanano.InvalidRMC
+		d.gga.FixQuality = nmeanano.Invalid
+		if flags&0x01 != 0 { // gnssFixOK
+			d.rmc.Validity = nmeanano.ValidRMC
+			switch {
+			case flags>>6 == 2:
+				d.gga.FixQuality = nmeanano.RTK
+			case flags>>6 == 1:
+				d.gga.FixQuality = nmeanano.FRTK
+			case flags&0x02 != 0:
+				d.gga.FixQuality = nmeanano.DGPS
+			case fixType == 1:
+				d.gga.FixQuality = nmeanano.EST
+			default:
+				d.gga.FixQuality = nmeanano.GPS
+			}
+		}
+		switch fixType {
+		case 2:
+			d.gsa.FixType = nmeanano.Fix2D
+		case 3, 4:
+			d.gsa.FixType = nmeanano.Fix3D
+		default:
+			d.gsa.FixType = nmeanano.FixNone
+		}
+		d.gsa.Mode = nmeanano.Auto
+		d.gga.NumSatellites = int64(p[23])
+		d.rmc.Longitude = float64(int32(binary.LittleEndian.Uint32(p[24:]))) * 1e-7
+		d.rmc.Latitude = float64(int32(binary.LittleEndian.Uint32(p[28:]))) * 1e-7
+		d.gga.Latitude, d.gga.Longitude = d.rmc.Latitude, d.rmc.Longitude
+		height := float64(int32(binary.LittleEndian.Uint32(p[32:]))) * 1e-3
+		d.gga.Altitude = float64(int32(binary.LittleEndian.Uint32(p[36:]))) * 1e-3
+		d.gga.Separation = height - d.gga.Altitude
+		ms := float64(int32(binary.LittleEndian.Uint32(p[60:]))) * 1e-3
+		d.rmc.Speed = ms * 3600 / 1852
+		d.rmc.Course = float64(int32(binary.LittleEndian.Uint32(p[64:]))) * 1e-5
+		d.vtg.TrueTrack, d.vtg.GroundSpeedKnots, d.vtg.GroundSpeedKPH = d.rmc.Course, d.rmc.Speed, ms*3.6
+		d.gsa.PDOP = float64(binary.LittleEndian.Uint16(p[76:])) * 0.01
+		d.gga.Time = d.rmc.Time
+		d.rmc.Raw, d.gga.Raw, d.gsa.Raw, d.vtg.Raw = r, r, r, r
+		return []nmeanano.Sentence{d.rmc, d.gga, d.gsa, d.vtg}, nil
+	case f.Class == ClassNAV && f.ID == IDNavSat:
+		if len(p) < 8 {
+			return nil, errShort(f)
+		}
+		n := int(p[5])
+		if len(p) < 8+n*12 {
+			return nil, errShort(f)
+		}
+		d.gsv.TotalMessages, d.gsv.MessageNumber, d.gsv.NumberSVsInView = 1, 1, int64(n)
+		d.gsv.Info = make([]nmeanano.GSVInfo, 0, n)
+		d.gsa.SV = make([]string, 0, n) // returned gsa keep their own slice
+		for i := 0; i < n; i++ {
+			sv := p[8+i*12:]
+			info := nmeanano.GSVInfo{
+				SVPRNNumber: prn(sv[0], sv[1]),
+				SNR:         int64(sv[2]),
+				Elevation:   int64(int8(sv[3])),
+				Azimuth:     int64(int16(binary.LittleEndian.Uint16(sv[4:]))),
+			}
+			if binary.LittleEndian.Uint32(sv[8:])&0x08 != 0 { // svUsed
+				d.gsa.SV = append(d.gsa.SV, strconv.FormatInt(info.SVPRNNumber, 10))
+			}
+			d.gsv.Info = append(d.gsv.Info, info)
+		}
+		d.gsv.Raw, d.gsa.Raw = r, r
+		return []nmeanano.Sentence{d.gsv, d.gsa}, nil
+	case f.Class == ClassNAV && f.ID == IDNavTime:
+		if len(p) < 20 {
+			return nil, errShort(f)
+		}
+		if p[19]&0x04 == 0 { // validUTC
+			return nil, nil
+		}
+		d.rmc.Date = nmeanano.Date{Valid: true, DD: int(p[15]), MM: int(p[14]), YY: int(binary.LittleEndian.Uint16(p[12:])) % 100}
+		d.setTime(p[16], p[17], p[18], int32(binary.LittleEndian.Uint32(p[8:])))
+		d.rmc.Raw = r
+		return []nmeanano.Sentence{d.rmc}, nil
+	case f.Class == ClassMON && f.ID == IDMonHW:
+		if len(p) < 60 {
+			return nil, errShort(f)
+		}
+		d.hw.NoisePerMS = int64(binary.LittleEndian.Uint16(p[16:]))
+		d.hw.AGCCount = int64(binary.LittleEndian.Uint16(p[18:]))
+		d.hw.AntennaStatus = lookup(_antennaStatus, p[20])
+		d.hw.AntennaPower = lookup(_antennaPower, p[21])
+		d.hw.JammingState = lookup(_jammingState, (p[22]>>2)&0x03)
+		d.hw.JamInd = int64(p[45])
+		d.hw.Raw = r
+		return []nmeanano.Sentence{d.hw}, nil
+	}
+	return nil, nil
+}
+
+// prn maps gnssId and svId to the u-blox extended nmea numbering, unknown ones keep the svId
+func prn(gnssID, svID byte) int64 {
+	id := int64(svID)
+	switch gnssID {
+	case GNSSSBAS:
+		if id >= 120 && id <= 151 {
+			return id - 87
+		}
+	case GNSSGalileo:
+		return id + 300
+	case GNSSBeiDou:
+		return id + 400
+	case GNSSIMES:
+		return id + 172
+	case GNSSQZSS:
+		return id + 192
+	case GNSSGLONASS:
+		if id >= 1 && id <= 32 {
+			return id + 64
+		}
+	}
+	return id
+}
+
+// setTime updates the rmc time, nano is the signed fraction of the second
+func (d *Decoder) setTime(hour, min, sec byte, nano int32) {
+	t := nmeanano.Time{Valid: true, Hour: int(hour), Minute: int(min), Second: int(sec)}
+	if nano > 0 {
+		t.Millisecond = int(math.Min(float64(nano/1000000), 999))
+	}
+	d.rmc.Time = t
+}
+
+// lookup ...
+func lookup(names []string, i byte) string {
+	if int(i) < len(names) {
+		return names[i]
+	}
+	return strconv.Itoa(int(i))
+}
+
+// errShort ...
+func errShort(f Frame) error {
+	return fmt.Errorf("ubx: %s payload too short [%d bytes]", name(f), len(f.Payload))
+}
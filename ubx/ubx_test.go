@@ -0,0 +1,265 @@
+package ubx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+
+	"paepcke.de/gpsinfo/nmeanano"
+)
+
+// _navTime is a NAV-TIMEUTC frame of 2026-10-19 12:34:56.25
+var _navTime = Encode(Frame{Class: ClassNAV, ID: IDNavTime, Payload: navTime(true)})
+
+// navTime returns a NAV-TIMEUTC payload
+func navTime(valid bool) []byte {
+	p := make([]byte, 20)
+	binary.LittleEndian.PutUint32(p[8:], 250000000)
+	binary.LittleEndian.PutUint16(p[12:], 2026)
+	p[14], p[15], p[16], p[17], p[18] = 10, 19, 12, 34, 56
+	if valid {
+		p[19] = 0x07
+	}
+	return p
+}
+
+// navPVT returns a NAV-PVT payload with a 3d fix
+func navPVT() []byte {
+	p := make([]byte, 92)
+	binary.LittleEndian.PutUint16(p[4:], 2026)
+	p[6], p[7], p[8], p[9], p[10], p[11] = 10, 19, 12, 34, 56, 0x03
+	binary.LittleEndian.PutUint32(p[16:], 500000000)
+	p[20], p[21], p[23] = 3, 0x01, 9
+	put32 := func(i int, v int32) { binary.LittleEndian.PutUint32(p[i:], uint32(v)) }
+	put32(24, -95736100) // lon
+	put32(28, 535642360) // lat
+	put32(32, 100000)    // height above ellipsoid
+	put32(36, 54000)     // height above msl
+	put32(60, 10000)     // ground speed
+	put32(64, 9000000)   // heading of motion
+	binary.LittleEndian.PutUint16(p[76:], 150)
+	return p
+}
+
+// sat is a NAV-SAT satellite
+type sat struct {
+	gnssID, svID, cno byte
+	elev              int8
+	azim              int16
+	used              bool
+}
+
+// navSat returns a NAV-SAT payload
+func navSat(sats ...sat) []byte {
+	p := make([]byte, 8, 8+12*len(sats))
+	p[4], p[5] = 1, byte(len(sats))
+	for _, s := range sats {
+		sv := make([]byte, 12)
+		sv[0], sv[1], sv[2], sv[3] = s.gnssID, s.svID, s.cno, byte(s.elev)
+		binary.LittleEndian.PutUint16(sv[4:], uint16(s.azim))
+		if s.used {
+			sv[8] = 0x08
+		}
+		p = append(p, sv...)
+	}
+	return p
+}
+
+// TestParseFrame ...
+func TestParseFrame(t *testing.T) {
+	valid := Encode(Frame{Class: ClassNAV, ID: IDNavTime, Payload: []byte{1, 2, 3}})
+	corrupt := bytes.Clone(valid)
+	corrupt[7]++
+	tests := []struct {
+		name string
+		raw  []byte
+		err  bool
+	}{
+		{name: "valid", raw: valid},
+		{name: "empty payload", raw: Encode(Frame{Class: ClassACK, ID: IDAckAck})},
+		{name: "empty", raw: nil, err: true},
+		{name: "too short", raw: valid[:7], err: true},
+		{name: "no sync", raw: append([]byte{Sync1, 0x00}, valid[2:]...), err: true},
+		{name: "length too long", raw: valid[:len(valid)-1], err: true},
+		{name: "length too short", raw: append(bytes.Clone(valid), 0), err: true},
+		{name: "checksum", raw: corrupt, err: true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFrame(tt.raw)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: ParseFrame = %+v, %v, want error %v", tt.name, f, err, tt.err)
+		}
+	}
+}
+
+// TestEncode ...
+func TestEncode(t *testing.T) {
+	for _, f := range []Frame{
+		{Class: ClassNAV, ID: IDNavPVT, Payload: navPVT()},
+		{Class: ClassCFG, ID: 0x08, Payload: []byte{0xE8, 0x03, 0x01, 0x00, 0x01, 0x00}},
+		{Class: ClassMON, ID: IDMonHW},
+	} {
+		raw := Encode(f)
+		if !IsFrame(raw) || len(raw) != len(f.Payload)+_frameOver {
+			t.Errorf("Encode(%s) = %X", name(f), raw)
+		}
+		got, err := ParseFrame(raw)
+		if err != nil || got.Class != f.Class || got.ID != f.ID || !bytes.Equal(got.Payload, f.Payload) {
+			t.Errorf("ParseFrame(Encode(%s)) = %+v, %v", name(f), got, err)
+		}
+	}
+	// CFG-RATE 1 Hz, checksum from the u-blox protocol specification
+	if raw := Encode(Frame{Class: ClassCFG, ID: 0x08, Payload: []byte{0xE8, 0x03, 0x01, 0x00, 0x01, 0x00}}); !bytes.Equal(raw[len(raw)-2:], []byte{0x01, 0x39}) {
+		t.Errorf("CFG-RATE checksum %X, want 0139", raw[len(raw)-2:])
+	}
+}
+
+// TestMatch ...
+func TestMatch(t *testing.T) {
+	oversized := []byte{Sync1, Sync2, ClassNAV, IDNavPVT, 0xFF, 0xFF}
+	corrupt := bytes.Clone(_navTime)
+	corrupt[10]++
+	tests := []struct {
+		name  string
+		data  []byte
+		atEOF bool
+		n     int
+		ok    bool
+	}{
+		{name: "frame", data: _navTime, n: len(_navTime), ok: true},
+		{name: "frame and text", data: append(bytes.Clone(_navTime), "$GPRMC"...), n: len(_navTime), ok: true},
+		{name: "sync only", data: _navTime[:1], ok: true},
+		{name: "partial header", data: _navTime[:4], ok: true},
+		{name: "partial payload", data: _navTime[:20], ok: true},
+		{name: "partial at eof", data: _navTime[:20], atEOF: true},
+		{name: "partial header at eof", data: _navTime[:4], atEOF: true},
+		{name: "false sync", data: []byte{Sync1, 'G', 'P'}},
+		{name: "oversized length", data: oversized},
+		{name: "checksum", data: corrupt},
+		{name: "text", data: []byte("$GPRMC,1*00\n")},
+		{name: "empty", data: nil, atEOF: true},
+	}
+	for _, tt := range tests {
+		if n, ok := Match(tt.data, tt.atEOF); n != tt.n || ok != tt.ok {
+			t.Errorf("%s: Match = %d, %v, want %d, %v", tt.name, n, ok, tt.n, tt.ok)
+		}
+	}
+}
+
+// TestDecode ...
+func TestDecode(t *testing.T) {
+	hw := make([]byte, 60)
+	binary.LittleEndian.PutUint16(hw[16:], 80)
+	binary.LittleEndian.PutUint16(hw[18:], 4000)
+	hw[20], hw[21], hw[22], hw[45] = 2, 1, 0x08, 17
+	d := NewDecoder()
+
+	s := decode(t, d, Frame{Class: ClassNAV, ID: IDNavPVT, Payload: navPVT()}, 4)
+	rmc, gga, gsa, vtg := s[0].(nmeanano.RMC), s[1].(nmeanano.GGA), s[2].(nmeanano.GSA), s[3].(nmeanano.VTG)
+	if rmc.Validity != nmeanano.ValidRMC || rmc.Date.String() != "19/10/26" || rmc.Time.String() != "12:34:56.5000" {
+		t.Errorf("NAV-PVT rmc = %s %s %s", rmc.Validity, rmc.Date, rmc.Time)
+	}
+	if math.Abs(rmc.Latitude-53.564236) > 1e-6 || math.Abs(rmc.Longitude+9.57361) > 1e-6 || math.Abs(rmc.Course-90) > 1e-9 {
+		t.Errorf("NAV-PVT rmc position = %v %v %v", rmc.Latitude, rmc.Longitude, rmc.Course)
+	}
+	if gga.FixQuality != nmeanano.GPS || gga.NumSatellites != 9 || gga.Altitude != 54 || gga.Separation != 46 || gga.Latitude != rmc.Latitude {
+		t.Errorf("NAV-PVT gga = %+v", gga)
+	}
+	if gsa.FixType != nmeanano.Fix3D || gsa.PDOP != 1.5 {
+		t.Errorf("NAV-PVT gsa = %+v", gsa)
+	}
+	if math.Abs(vtg.GroundSpeedKPH-36) > 1e-9 || math.Abs(vtg.GroundSpeedKnots-36/1.852) > 1e-9 {
+		t.Errorf("NAV-PVT vtg = %+v", vtg)
+	}
+
+	s = decode(t, d, Frame{Class: ClassNAV, ID: IDNavSat, Payload: navSat(
+		sat{gnssID: GNSSGPS, svID: 5, cno: 40, elev: 45, azim: 180, used: true},
+		sat{gnssID: GNSSGLONASS, svID: 3, cno: 30, elev: -2, azim: 10, used: true},
+		sat{gnssID: GNSSGalileo, svID: 5, cno: 35},
+		sat{gnssID: GNSSSBAS, svID: 123},
+		sat{gnssID: GNSSBeiDou, svID: 12, used: true},
+		sat{gnssID: GNSSQZSS, svID: 1},
+		sat{gnssID: GNSSGLONASS, svID: 255},
+	)}, 2)
+	gsv := s[0].(nmeanano.GSV)
+	var prns []int64
+	for _, info := range gsv.Info {
+		prns = append(prns, info.SVPRNNumber)
+	}
+	if want := []int64{5, 67, 305, 36, 412, 193, 255}; gsv.NumberSVsInView != 7 || !slices.Equal(prns, want) {
+		t.Errorf("NAV-SAT gsv prns = %v, want %v", prns, want)
+	}
+	if info := gsv.Info[1]; info.SNR != 30 || info.Elevation != -2 || info.Azimuth != 10 {
+		t.Errorf("NAV-SAT gsv info = %+v", info)
+	}
+	if used := s[1].(nmeanano.GSA).SV; !slices.Equal(used, []string{"5", "67", "412"}) {
+		t.Errorf("NAV-SAT gsa used = %v", used)
+	}
+
+	s = decode(t, d, Frame{Class: ClassNAV, ID: IDNavTime, Payload: navTime(true)}, 1)
+	if rmc := s[0].(nmeanano.RMC); rmc.Time.String() != "12:34:56.2500" || rmc.Date.String() != "19/10/26" {
+		t.Errorf("NAV-TIMEUTC rmc = %s %s", rmc.Date, rmc.Time)
+	}
+	decode(t, d, Frame{Class: ClassNAV, ID: IDNavTime, Payload: navTime(false)}, 0)
+
+	s = decode(t, d, Frame{Class: ClassMON, ID: IDMonHW, Payload: hw}, 1)
+	if m := s[0].(MonHW); m.AntennaStatus != "OK" || m.AntennaPower != "ON" || m.JammingState != "WARNING" || m.JamInd != 17 || m.NoisePerMS != 80 || m.AGCCount != 4000 || m.Talker != TalkerUBX || m.Type != TypeMonHW {
+		t.Errorf("MON-HW = %+v", m)
+	}
+
+	decode(t, d, Frame{Class: ClassCFG, ID: 0x08, Payload: []byte{1, 2}}, 0)
+	for _, f := range []Frame{
+		{Class: ClassNAV, ID: IDNavPVT, Payload: make([]byte, 91)},
+		{Class: ClassNAV, ID: IDNavSat, Payload: make([]byte, 7)},
+		{Class: ClassNAV, ID: IDNavSat, Payload: navSat(sat{}, sat{})[:20]},
+		{Class: ClassNAV, ID: IDNavTime, Payload: make([]byte, 19)},
+		{Class: ClassMON, ID: IDMonHW, Payload: make([]byte, 59)},
+	} {
+		if s, err := d.Decode(f); err == nil {
+			t.Errorf("Decode(short %s) = %v, want error", name(f), s)
+		}
+	}
+}
+
+// decode decodes the frame, fails unless it returns n sentences
+func decode(t *testing.T, d *Decoder, f Frame, n int) []nmeanano.Sentence {
+	t.Helper()
+	s, err := d.Decode(f)
+	if err != nil || len(s) != n {
+		t.Fatalf("Decode(%s) = %d sentences, %v, want %d", name(f), len(s), err, n)
+	}
+	return s
+}
+
+// TestDecodeOwnSlice checks that returned sentences are not changed by later frames
+func TestDecodeOwnSlice(t *testing.T) {
+	d := NewDecoder()
+	first := decode(t, d, Frame{Class: ClassNAV, ID: IDNavSat, Payload: navSat(sat{svID: 1, used: true}, sat{svID: 2, used: true}, sat{svID: 3, used: true})}, 2)
+	decode(t, d, Frame{Class: ClassNAV, ID: IDNavSat, Payload: navSat(sat{svID: 7, used: true}, sat{svID: 8, used: true}, sat{svID: 9, used: true})}, 2)
+	if used := first[1].(nmeanano.GSA).SV; !slices.Equal(used, []string{"1", "2", "3"}) {
+		t.Errorf("first gsa used = %v, want [1 2 3]", used)
+	}
+}
+
+// TestParseAck ...
+func TestParseAck(t *testing.T) {
+	tests := []struct {
+		f   Frame
+		ack Ack
+		err bool
+	}{
+		{f: Frame{Class: ClassACK, ID: IDAckAck, Payload: []byte{ClassCFG, 0x08}}, ack: Ack{Class: ClassCFG, ID: 0x08, Ack: true}},
+		{f: Frame{Class: ClassACK, ID: IDAckNak, Payload: []byte{ClassCFG, 0x01}}, ack: Ack{Class: ClassCFG, ID: 0x01}},
+		{f: Frame{Class: ClassACK, ID: IDAckAck, Payload: []byte{ClassCFG}}, err: true},
+		{f: Frame{Class: ClassACK, ID: 0x02, Payload: []byte{ClassCFG, 0x08}}, err: true},
+		{f: Frame{Class: ClassCFG, ID: IDAckAck, Payload: []byte{ClassCFG, 0x08}}, err: true},
+	}
+	for _, tt := range tests {
+		ack, err := ParseAck(tt.f)
+		if (err != nil) != tt.err || ack != tt.ack {
+			t.Errorf("ParseAck(%s %X) = %+v, %v, want %+v, error %v", name(tt.f), tt.f.Payload, ack, err, tt.ack, tt.err)
+		}
+	}
+}